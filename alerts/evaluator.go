@@ -0,0 +1,246 @@
+package alerts
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+// resendFactor multiplies the evaluation interval to get the end time of firing alerts,
+// Alertmanager resolves alerts which are not re-sent before their end time
+const resendFactor = 4
+
+// Alert is an alert of the Alertmanager v2 API
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// activeAlert is a pending or firing alert of a series
+type activeAlert struct {
+	labels      map[string]string
+	annotations map[string]string
+	activeSince time.Time
+	firing      bool
+}
+
+// Evaluator evaluates the rules on the gathered metrics and posts firing and resolved alerts to Alertmanager
+type Evaluator struct {
+	alertsContext *config.AlertsContext
+	rules         []Rule
+	client        *http.Client
+	volatile      map[string]bool         // labels left out of the alerts, such as PIDs changing on restarts and statuses
+	active        map[string]*activeAlert // by rule and series labels
+}
+
+// NewEvaluator creates the evaluator, the volatile labels are left out of the alerts so that a process
+// restart or a change of the failure state neither resets the for duration nor starts a new alert,
+// the annotations are still expanded with them
+func NewEvaluator(alertsContext *config.AlertsContext, rules []Rule, volatileLabels []string) (*Evaluator, error) {
+	client, err := config.NewHTTPClient(alertsContext.TLS, alertsContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	volatile := make(map[string]bool, len(volatileLabels))
+	for _, name := range volatileLabels {
+		volatile[name] = true
+	}
+	return &Evaluator{
+		alertsContext: alertsContext,
+		rules:         rules,
+		client:        client,
+		volatile:      volatile,
+		active:        make(map[string]*activeAlert),
+	}, nil
+}
+
+// Run evaluates the rules every interval until ctx is done
+func (e *Evaluator) Run(ctx context.Context, gatherer prometheus.Gatherer) {
+	ticker := time.NewTicker(e.alertsContext.Interval)
+	defer ticker.Stop()
+	for {
+		families, err := gatherer.Gather()
+		if err != nil {
+			zap.L().Warn("Failed to gather some metrics for alert rules", zap.Error(err))
+		}
+		if err := e.send(ctx, e.evaluate(families, time.Now())); err != nil {
+			zap.L().Error("Failed to send alerts", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate updates the active alerts and returns the firing alerts and the alerts resolved since the last evaluation
+func (e *Evaluator) evaluate(families []*dto.MetricFamily, now time.Time) []Alert {
+	seen := make(map[string]bool)
+	for i := range e.rules {
+		rule := &e.rules[i]
+		for _, family := range families {
+			if family.GetName() != rule.Metric {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				labels := metricLabels(metric)
+				value, ok := metricValue(family.GetType(), metric)
+				if !ok || !rule.matches(family.GetName(), labels) || !rule.holds(value) {
+					continue
+				}
+				alertLabels := make(map[string]string, len(labels)+len(rule.Labels)+1)
+				for name, v := range labels {
+					if !e.volatile[name] {
+						alertLabels[name] = v
+					}
+				}
+				for name, v := range rule.Labels {
+					alertLabels[name] = v
+				}
+				alertLabels["alertname"] = rule.Alert
+				key := alertKey(alertLabels)
+				seen[key] = true
+				alert, ok := e.active[key]
+				if !ok {
+					alert = &activeAlert{activeSince: now}
+					e.active[key] = alert
+				}
+				alert.labels = alertLabels
+				alert.annotations = rule.expandAnnotations(labels, value)
+				alert.firing = now.Sub(alert.activeSince) >= rule.For
+			}
+		}
+	}
+
+	var alerts []Alert
+	for _, key := range sortedKeys(e.active) {
+		alert := e.active[key]
+		switch {
+		case seen[key] && alert.firing:
+			alerts = append(alerts, Alert{Labels: alert.labels, Annotations: alert.annotations, StartsAt: alert.activeSince, EndsAt: now.Add(resendFactor * e.alertsContext.Interval)})
+		case !seen[key]:
+			if alert.firing {
+				alerts = append(alerts, Alert{Labels: alert.labels, Annotations: alert.annotations, StartsAt: alert.activeSince, EndsAt: now})
+			}
+			delete(e.active, key)
+		}
+	}
+	return alerts
+}
+
+// send posts the alerts to all Alertmanagers
+func (e *Evaluator) send(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, url := range e.alertsContext.AlertmanagerURLs {
+		if err := e.post(ctx, strings.TrimSuffix(url, "/")+"/api/v2/alerts", body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot send alerts, error: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (e *Evaluator) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("alertmanager '%s' returned HTTP status %s: %s", url, resp.Status, bytes.TrimSpace(message))
+}
+
+func (r *Rule) expandAnnotations(labels map[string]string, value float64) map[string]string {
+	if len(r.annotations) == 0 {
+		return nil
+	}
+	annotations := make(map[string]string, len(r.annotations))
+	data := struct {
+		Labels map[string]string
+		Value  float64
+	}{Labels: labels, Value: value}
+	for name, tmpl := range r.annotations {
+		var text strings.Builder
+		if err := tmpl.Execute(&text, data); err != nil {
+			text.Reset()
+			text.WriteString(r.Annotations[name])
+		}
+		annotations[name] = text.String()
+	}
+	return annotations
+}
+
+func metricLabels(metric *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(metric.GetLabel()))
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+// metricValue returns the value of gauges, counters and untyped metrics
+func metricValue(metricType dto.MetricType, metric *dto.Metric) (float64, bool) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return metric.GetUntyped().GetValue(), true
+	default:
+		return 0, false
+	}
+}
+
+// alertKey identifies an alert by its labels
+func alertKey(labels map[string]string) string {
+	var key strings.Builder
+	for _, name := range sortedKeys(labels) {
+		key.WriteString(name)
+		key.WriteByte('\xff')
+		key.WriteString(labels[name])
+		key.WriteByte('\xff')
+	}
+	return key.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package alerts
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type alertmanagerStub struct {
+	posts [][]Alert
+}
+
+func (s *alertmanagerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var alerts []Alert
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" || json.NewDecoder(r.Body).Decode(&alerts) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.posts = append(s.posts, alerts)
+}
+
+func newTestEvaluator(t *testing.T, stub *alertmanagerStub) *Evaluator {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	rules, err := ParseRules([]byte(testRules))
+	require.NoError(t, err)
+	evaluator, err := NewEvaluator(&config.AlertsContext{
+		AlertmanagerURLs: []string{server.URL + "/"},
+		Interval:         30 * time.Second,
+		Timeout:          time.Second,
+	}, rules, []string{"process_pid", "monitoring_status", "service_status"})
+	require.NoError(t, err)
+	return evaluator
+}
+
+func gatherStatus(t *testing.T, status string) []*dto.MetricFamily {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "boshi_monit_process_status_info",
+		Help:        "Monit process and monitoring status information",
+		ConstLabels: prometheus.Labels{"bosh_deployment": "test-dev"},
+	}, []string{"process_name", "service_status"})
+	gauge.WithLabelValues("nats", status).Set(1)
+	return gather(t, gauge)
+}
+
+func gatherStatusWithPid(t *testing.T, status, pid string) []*dto.MetricFamily {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "boshi_monit_process_status_info",
+		Help:        "Monit process and monitoring status information",
+		ConstLabels: prometheus.Labels{"bosh_deployment": "test-dev"},
+	}, []string{"process_name", "process_pid", "service_status"})
+	gauge.WithLabelValues("nats", pid, status).Set(1)
+	return gather(t, gauge)
+}
+
+func gather(t *testing.T, gauge *prometheus.GaugeVec) []*dto.MetricFamily {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(gauge))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	return families
+}
+
+func TestEvaluator_FiringAndResolved(t *testing.T) {
+	stub := &alertmanagerStub{}
+	evaluator := newTestEvaluator(t, stub)
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0).UTC()
+
+	alerts := evaluator.evaluate(gatherStatus(t, "Does not exist"), start)
+	assert.Empty(t, alerts, "the alert is pending during the for duration")
+	require.NoError(t, evaluator.send(ctx, alerts))
+	assert.Empty(t, stub.posts)
+
+	firingAt := start.Add(2 * time.Minute)
+	require.NoError(t, evaluator.send(ctx, evaluator.evaluate(gatherStatus(t, "Does not exist"), firingAt)))
+	require.Len(t, stub.posts, 1)
+	require.Len(t, stub.posts[0], 1)
+	firing := stub.posts[0][0]
+	assert.Equal(t, map[string]string{
+		"alertname":       "BoshProcessNotRunning",
+		"bosh_deployment": "test-dev",
+		"process_name":    "nats",
+		"severity":        "critical",
+	}, firing.Labels)
+	assert.Equal(t, map[string]string{"summary": "nats is Does not exist"}, firing.Annotations)
+	assert.Equal(t, start, firing.StartsAt)
+	assert.Equal(t, firingAt.Add(2*time.Minute), firing.EndsAt)
+
+	resolvedAt := firingAt.Add(30 * time.Second)
+	require.NoError(t, evaluator.send(ctx, evaluator.evaluate(gatherStatus(t, "running"), resolvedAt)))
+	require.Len(t, stub.posts, 2)
+	require.Len(t, stub.posts[1], 1)
+	assert.Equal(t, resolvedAt, stub.posts[1][0].EndsAt)
+	assert.Empty(t, evaluator.active)
+
+	assert.Empty(t, evaluator.evaluate(gatherStatus(t, "running"), resolvedAt.Add(30*time.Second)), "resolved alerts are sent once")
+}
+
+func TestEvaluator_PendingNotResolved(t *testing.T) {
+	evaluator := newTestEvaluator(t, &alertmanagerStub{})
+	start := time.Now()
+	assert.Empty(t, evaluator.evaluate(gatherStatus(t, "Does not exist"), start))
+	assert.Empty(t, evaluator.evaluate(gatherStatus(t, "running"), start.Add(time.Minute)), "pending alerts are not resolved")
+	assert.Empty(t, evaluator.active)
+}
+
+func TestEvaluator_RestartKeepsAlert(t *testing.T) {
+	evaluator := newTestEvaluator(t, &alertmanagerStub{})
+	start := time.Unix(1700000000, 0).UTC()
+	assert.Empty(t, evaluator.evaluate(gatherStatusWithPid(t, "Does not exist", "42"), start))
+
+	alerts := evaluator.evaluate(gatherStatusWithPid(t, "Does not exist", "43"), start.Add(2*time.Minute))
+	require.Len(t, alerts, 1, "a restart does not reset the for duration")
+	assert.Equal(t, start, alerts[0].StartsAt)
+	assert.NotContains(t, alerts[0].Labels, "process_pid")
+}
+
+func TestEvaluator_StatusChangeKeepsAlert(t *testing.T) {
+	evaluator := newTestEvaluator(t, &alertmanagerStub{})
+	start := time.Unix(1700000000, 0).UTC()
+	assert.Empty(t, evaluator.evaluate(gatherStatus(t, "Does not exist"), start))
+
+	alerts := evaluator.evaluate(gatherStatus(t, "Execution failed"), start.Add(2*time.Minute))
+	require.Len(t, alerts, 1, "a change of the failure state does not reset the for duration")
+	assert.Equal(t, start, alerts[0].StartsAt)
+	assert.NotContains(t, alerts[0].Labels, "service_status")
+	assert.Equal(t, map[string]string{"summary": "nats is Execution failed"}, alerts[0].Annotations)
+}
@@ -0,0 +1,157 @@
+package alerts
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var reMatcher = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"(.*)"\s*$`)
+
+// RuleFile is the YAML document of the alert rules, for example:
+//
+//	rules:
+//	  - alert: BoshProcessNotRunning
+//	    metric: boshi_monit_process_status_info
+//	    matchers: ['service_status!="running"']
+//	    op: "=="
+//	    threshold: 1
+//	    for: 2m
+//	    labels: {severity: critical}
+//	    annotations: {summary: "{{ .Labels.process_name }} is {{ .Labels.service_status }}"}
+type RuleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule fires an alert for every series of the metric matching the label matchers
+// whose value compared with the threshold is true for the For duration
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Metric      string            `yaml:"metric"`
+	Matchers    []string          `yaml:"matchers"` // PromQL style label matchers, e.g. process_name=~"nats.*"
+	Op          string            `yaml:"op"`       // one of >, >=, <, <=, ==, !=
+	Threshold   float64           `yaml:"threshold"`
+	For         time.Duration     `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"` // Go templates, the fields are .Labels and .Value
+
+	matchers    []matcher
+	annotations map[string]*template.Template
+}
+
+type matcher struct {
+	name  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (m *matcher) matches(labels map[string]string) bool {
+	value := labels[m.name]
+	switch m.op {
+	case "=":
+		return value == m.value
+	case "!=":
+		return value != m.value
+	case "=~":
+		return m.re.MatchString(value)
+	default:
+		return !m.re.MatchString(value)
+	}
+}
+
+// LoadRules reads and validates the rules file
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read alert rules file '%s', error: %v", path, err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules parses and validates the YAML rules
+func ParseRules(data []byte) ([]Rule, error) {
+	var file RuleFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("cannot parse alert rules, error: %v", err)
+	}
+	for i := range file.Rules {
+		if err := file.Rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return file.Rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Alert == "" || r.Metric == "" {
+		return fmt.Errorf("alert rule '%s' must have an alert name and a metric", r.Alert)
+	}
+	switch r.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("alert rule '%s' has an unknown op '%s'", r.Alert, r.Op)
+	}
+	for _, expr := range r.Matchers {
+		parts := reMatcher.FindStringSubmatch(expr)
+		if parts == nil {
+			return fmt.Errorf("alert rule '%s' has an invalid matcher '%s'", r.Alert, expr)
+		}
+		m := matcher{name: parts[1], op: parts[2], value: parts[3]}
+		if m.op == "=~" || m.op == "!~" {
+			re, err := regexp.Compile("^(?:" + m.value + ")$")
+			if err != nil {
+				return fmt.Errorf("alert rule '%s' has an invalid matcher regexp '%s', error: %v", r.Alert, m.value, err)
+			}
+			m.re = re
+		}
+		r.matchers = append(r.matchers, m)
+	}
+	r.annotations = make(map[string]*template.Template, len(r.Annotations))
+	for name, text := range r.Annotations {
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return fmt.Errorf("alert rule '%s' has an invalid annotation '%s', error: %v", r.Alert, name, err)
+		}
+		r.annotations[name] = tmpl
+	}
+	return nil
+}
+
+// matches reports whether the series is selected by the rule
+func (r *Rule) matches(name string, labels map[string]string) bool {
+	if name != r.Metric {
+		return false
+	}
+	for i := range r.matchers {
+		if !r.matchers[i].matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds reports whether the condition of the rule is true for the value
+func (r *Rule) holds(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	default:
+		return value != r.Threshold
+	}
+}
@@ -0,0 +1,58 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - alert: BoshProcessNotRunning
+    metric: boshi_monit_process_status_info
+    matchers: ['service_status!="running"', 'process_name=~"nats.*"']
+    op: "=="
+    threshold: 1
+    for: 2m
+    labels: {severity: critical}
+    annotations: {summary: "{{ .Labels.process_name }} is {{ .Labels.service_status }}"}
+  - alert: BoshDiskStoreFull
+    metric: boshi_system_disk_store_usage_ratio
+    op: ">"
+    threshold: 0.9
+`
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, 2*time.Minute, rules[0].For)
+	assert.Equal(t, map[string]string{"severity": "critical"}, rules[0].Labels)
+
+	rule := &rules[0]
+	assert.True(t, rule.matches("boshi_monit_process_status_info", map[string]string{"process_name": "nats", "service_status": "Does not exist"}))
+	assert.False(t, rule.matches("boshi_monit_process_status_info", map[string]string{"process_name": "nats", "service_status": "running"}))
+	assert.False(t, rule.matches("boshi_monit_process_status_info", map[string]string{"process_name": "bosh-dns", "service_status": "Does not exist"}))
+	assert.False(t, rule.matches("boshi_monit_uptime_seconds", map[string]string{"process_name": "nats", "service_status": "Does not exist"}))
+	assert.True(t, rules[1].holds(0.95))
+	assert.False(t, rules[1].holds(0.9))
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":      "rules: [{alert: A, metric: m, op: '>', treshold: 1}]",
+		"missing metric":     "rules: [{alert: A, op: '>'}]",
+		"unknown op":         "rules: [{alert: A, metric: m, op: '=>'}]",
+		"invalid matcher":    "rules: [{alert: A, metric: m, op: '>', matchers: ['name:value']}]",
+		"invalid regexp":     "rules: [{alert: A, metric: m, op: '>', matchers: ['name=~\"(\"']}]",
+		"invalid annotation": "rules: [{alert: A, metric: m, op: '>', annotations: {summary: '{{ .Labels'}}]",
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRules([]byte(rules))
+			assert.Error(t, err)
+		})
+	}
+}
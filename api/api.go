@@ -0,0 +1,28 @@
+package api
+
+import (
+	"boshi_exporter/fetchers"
+	"net/http"
+)
+
+// Prefix is the path prefix of all API endpoints
+const Prefix = "/api/v1/"
+
+// API serves the state of the Bosh instance as JSON documents
+type API struct {
+	snapshots *fetchers.Snapshots
+	mux       *http.ServeMux
+}
+
+var _ http.Handler = (*API)(nil)
+
+func NewAPI(snapshots *fetchers.Snapshots) *API {
+	a := &API{snapshots: snapshots, mux: http.NewServeMux()}
+	a.mux.HandleFunc("GET /api/v1/status", a.handleStatus)
+	a.mux.HandleFunc("GET /api/v1/status/processes/{name}", a.handleProcessStatus)
+	return a
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
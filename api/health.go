@@ -0,0 +1,108 @@
+package api
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// Monit states of a ready process
+const (
+	readyStatus           = "running"
+	readyMonitoringStatus = "monitored"
+)
+
+// HealthStatus is the document served by /healthz and /readyz
+type HealthStatus struct {
+	SchemaVersion int             `json:"schema_version"`
+	Status        string          `json:"status"` // ok or failing
+	Failing       []FailingStatus `json:"failing,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// FailingStatus describes a Monit process which is not ready
+type FailingStatus struct {
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	MonitoringStatus string `json:"monitoring_status"`
+}
+
+// Health serves the liveness of the exporter and the readiness of the Bosh instance
+type Health struct {
+	snapshots      *fetchers.Snapshots
+	processInclude *regexp.Regexp // nil includes all processes
+	processExclude *regexp.Regexp // nil excludes no process
+}
+
+func NewHealth(readinessContext *config.ReadinessContext, snapshots *fetchers.Snapshots) (*Health, error) {
+	processInclude, err := compileAnchored(readinessContext.ProcessInclude)
+	if err != nil {
+		return nil, err
+	}
+	processExclude, err := compileAnchored(readinessContext.ProcessExclude)
+	if err != nil {
+		return nil, err
+	}
+	return &Health{snapshots: snapshots, processInclude: processInclude, processExclude: processExclude}, nil
+}
+
+// Healthz reports that the exporter is alive, it does not depend on the state of the instance
+func (h *Health) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, HealthStatus{SchemaVersion: StatusSchemaVersion, Status: "ok"})
+}
+
+// Readyz reports whether all checked Monit processes are running and monitored
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.snapshots.Monit.Get(r.Context())
+	if snapshot.Err != nil || snapshot.Value == nil {
+		message := "monit status is not available"
+		if snapshot.Err != nil {
+			message = snapshot.Err.Error()
+		}
+		writeJSON(w, http.StatusServiceUnavailable, HealthStatus{SchemaVersion: StatusSchemaVersion, Status: "failing", Error: message})
+		return
+	}
+	failing := h.failingProcesses(snapshot.Value)
+	if len(failing) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, HealthStatus{SchemaVersion: StatusSchemaVersion, Status: "failing", Failing: failing})
+		return
+	}
+	writeJSON(w, http.StatusOK, HealthStatus{SchemaVersion: StatusSchemaVersion, Status: "ok"})
+}
+
+// failingProcesses returns the checked processes which are not running or not monitored, ordered by name
+func (h *Health) failingProcesses(stat *fetchers.MonitStat) []FailingStatus {
+	var failing []FailingStatus
+	for name, process := range stat.Processes {
+		if !h.isChecked(name) {
+			continue
+		}
+		if process.Status != readyStatus || process.MonitoringStatus != readyMonitoringStatus {
+			failing = append(failing, FailingStatus{Name: name, Status: process.Status, MonitoringStatus: process.MonitoringStatus})
+		}
+	}
+	sort.Slice(failing, func(i, j int) bool { return failing[i].Name < failing[j].Name })
+	return failing
+}
+
+func (h *Health) isChecked(name string) bool {
+	if h.processInclude != nil && !h.processInclude.MatchString(name) {
+		return false
+	}
+	return h.processExclude == nil || !h.processExclude.MatchString(name)
+}
+
+// compileAnchored compiles a regexp matching whole names, an empty expression returns nil
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile process name regexp '%s', error: %v", expr, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHealth(t *testing.T, readinessContext *config.ReadinessContext, processes map[string]fetchers.MonitProcessStatus, monitErr error) *Health {
+	snapshots := newTestSnapshots(nil)
+	snapshots.Monit = fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+		if monitErr != nil {
+			return nil, monitErr
+		}
+		return &fetchers.MonitStat{Processes: processes}, nil
+	})
+	health, err := NewHealth(readinessContext, snapshots)
+	require.NoError(t, err)
+	return health
+}
+
+func TestHealth_Healthz(t *testing.T) {
+	health := newTestHealth(t, &config.ReadinessContext{}, nil, errors.New("monit is down"))
+	recorder, document := get(t, http.HandlerFunc(health.Healthz), "/healthz")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok", document["status"])
+}
+
+func TestHealth_Readyz(t *testing.T) {
+	processes := map[string]fetchers.MonitProcessStatus{
+		"nats":           {Status: "running", MonitoringStatus: "monitored"},
+		"nats-tls":       {Status: "Does not exist", MonitoringStatus: "monitored"},
+		"bosh-dns":       {Status: "running", MonitoringStatus: "not monitored"},
+		"loggr-syslog-1": {Status: "initializing", MonitoringStatus: "monitored"},
+	}
+
+	tests := []struct {
+		name             string
+		readinessContext *config.ReadinessContext
+		wantCode         int
+		wantFailing      []string
+	}{
+		{name: "all processes", readinessContext: &config.ReadinessContext{}, wantCode: http.StatusServiceUnavailable, wantFailing: []string{"bosh-dns", "loggr-syslog-1", "nats-tls"}},
+		{name: "include", readinessContext: &config.ReadinessContext{ProcessInclude: "nats.*"}, wantCode: http.StatusServiceUnavailable, wantFailing: []string{"nats-tls"}},
+		{name: "include and exclude", readinessContext: &config.ReadinessContext{ProcessInclude: "nats.*", ProcessExclude: "nats-tls"}, wantCode: http.StatusOK},
+		{name: "anchored", readinessContext: &config.ReadinessContext{ProcessInclude: "nats"}, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := newTestHealth(t, tt.readinessContext, processes, nil)
+			recorder, document := get(t, http.HandlerFunc(health.Readyz), "/readyz")
+			assert.Equal(t, tt.wantCode, recorder.Code)
+			var failing []string
+			if list, ok := document["failing"].([]any); ok {
+				for _, process := range list {
+					failing = append(failing, process.(map[string]any)["name"].(string))
+				}
+			}
+			assert.Equal(t, tt.wantFailing, failing)
+		})
+	}
+}
+
+func TestHealth_ReadyzMonitUnavailable(t *testing.T) {
+	health := newTestHealth(t, &config.ReadinessContext{}, nil, errors.New("monit is down"))
+	recorder, document := get(t, http.HandlerFunc(health.Readyz), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "failing", document["status"])
+	assert.Equal(t, "monit is down", document["error"])
+}
+
+func TestNewHealth_InvalidRegexp(t *testing.T) {
+	_, err := NewHealth(&config.ReadinessContext{ProcessExclude: "("}, newTestSnapshots(nil))
+	assert.Error(t, err)
+}
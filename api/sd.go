@@ -0,0 +1,155 @@
+package api
+
+import (
+	"boshi_exporter/fetchers"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Target labels of the exporter, they are meta labels because the exporter already attaches the bosh_* labels
+// to every series, a target label of the same name would rename them to exported_* with honor_labels: false.
+// Prometheus drops the meta labels after relabeling, use them to select targets or copy them to other names:
+//
+//	relabel_configs:
+//	  - source_labels: [__meta_bosh_deployment]
+//	    regex: cf|redis
+//	    action: keep
+//	  - source_labels: [__meta_bosh_az]
+//	    target_label: zone
+const (
+	sdDeploymentLabel = "__meta_bosh_deployment"
+	sdJobLabel        = "__meta_bosh_job"
+	sdIndexLabel      = "__meta_bosh_index"
+	sdAZLabel         = "__meta_bosh_az"
+)
+
+// TargetGroup is an entry of the Prometheus http_sd and file_sd documents
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// PeerLister lists the peers aggregated by the exporter as host:port
+type PeerLister interface {
+	Targets() ([]string, error)
+}
+
+// ServiceDiscovery describes the scrape target of the exporter and of its aggregated peers
+type ServiceDiscovery struct {
+	port      string
+	snapshots *fetchers.Snapshots
+	peers     PeerLister // nil if the exporter does not aggregate peers
+}
+
+var _ http.Handler = (*ServiceDiscovery)(nil)
+
+// NewServiceDiscovery creates the service discovery of the exporter listening on listenAddress, peers may be nil
+func NewServiceDiscovery(listenAddress string, snapshots *fetchers.Snapshots, peers PeerLister) (*ServiceDiscovery, error) {
+	_, port, err := net.SplitHostPort(listenAddress)
+	if err != nil || port == "" {
+		return nil, fmt.Errorf("cannot get the port of listen address '%s'", listenAddress)
+	}
+	return &ServiceDiscovery{port: port, snapshots: snapshots, peers: peers}, nil
+}
+
+// TargetGroups returns the target of the exporter at its Bosh network IP and the targets of the peers
+func (s *ServiceDiscovery) TargetGroups(ctx context.Context) ([]TargetGroup, error) {
+	snapshot := s.snapshots.Spec.Get(ctx)
+	if snapshot.Value == nil {
+		return nil, fmt.Errorf("instance spec is not available, error: %v", snapshot.Err)
+	}
+	spec := snapshot.Value
+	ip := spec.IP()
+	if ip == "" {
+		return nil, errors.New("instance spec has no network IP")
+	}
+	self := net.JoinHostPort(ip, s.port)
+	groups := []TargetGroup{{
+		Targets: []string{self},
+		Labels: map[string]string{
+			sdDeploymentLabel: spec.Deployment,
+			sdJobLabel:        spec.Name,
+			sdIndexLabel:      strconv.Itoa(spec.Index),
+			sdAZLabel:         spec.AZ,
+		},
+	}}
+	if s.peers != nil {
+		peers, err := s.peers.Targets()
+		if err != nil {
+			return nil, err
+		}
+		peers = slices.DeleteFunc(peers, func(peer string) bool { return peer == self })
+		if len(peers) > 0 {
+			groups = append(groups, TargetGroup{Targets: peers})
+		}
+	}
+	return groups, nil
+}
+
+// ServeHTTP serves the target groups in the Prometheus http_sd format
+func (s *ServiceDiscovery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.TargetGroups(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// WriteFile writes the target groups to a Prometheus file_sd JSON file, the file is replaced atomically
+func (s *ServiceDiscovery) WriteFile(ctx context.Context, path string) error {
+	groups, err := s.TargetGroups(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create file_sd file '%s', error: %v", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write file_sd file '%s', error: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write file_sd file '%s', error: %v", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("cannot write file_sd file '%s', error: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot write file_sd file '%s', error: %v", path, err)
+	}
+	return nil
+}
+
+// RunFileWriter writes the file_sd file immediately and then on every interval until ctx is done
+func (s *ServiceDiscovery) RunFileWriter(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.WriteFile(ctx, path); err != nil {
+			zap.L().Warn("Failed to write file_sd file", zap.String("path", path), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
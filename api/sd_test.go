@@ -0,0 +1,70 @@
+package api
+
+import (
+	"boshi_exporter/collectors"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticPeers []string
+
+func (p staticPeers) Targets() ([]string, error) {
+	return p, nil
+}
+
+func TestServiceDiscovery(t *testing.T) {
+	sd, err := NewServiceDiscovery(":9191", newTestSnapshots(nil), nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	sd.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/sd", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[{
+		"targets": ["10.0.1.5:9191"],
+		"labels": {
+			"__meta_bosh_deployment": "test-dev",
+			"__meta_bosh_job": "nats",
+			"__meta_bosh_index": "1",
+			"__meta_bosh_az": "z2"
+		}
+	}]`, recorder.Body.String())
+}
+
+func TestServiceDiscovery_LabelsDoNotClash(t *testing.T) {
+	sd, err := NewServiceDiscovery(":9191", newTestSnapshots(nil), nil)
+	require.NoError(t, err)
+	groups, err := sd.TargetGroups(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, groups[0].Labels)
+	for name := range groups[0].Labels {
+		assert.NotContains(t, collectors.LabelNames, name, "target labels must not rename the labels of the exported series")
+	}
+}
+
+func TestServiceDiscovery_Peers(t *testing.T) {
+	sd, err := NewServiceDiscovery("0.0.0.0:9191", newTestSnapshots(nil), staticPeers{"10.0.1.5:9191", "10.0.1.6:9191", "10.0.1.7:9191"})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "boshi.json")
+	require.NoError(t, sd.WriteFile(context.Background(), path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var groups []TargetGroup
+	require.NoError(t, json.Unmarshal(data, &groups))
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"10.0.1.5:9191"}, groups[0].Targets)
+	assert.Equal(t, TargetGroup{Targets: []string{"10.0.1.6:9191", "10.0.1.7:9191"}}, groups[1])
+}
+
+func TestServiceDiscovery_InvalidListenAddress(t *testing.T) {
+	_, err := NewServiceDiscovery("localhost", newTestSnapshots(nil), nil)
+	require.Error(t, err)
+}
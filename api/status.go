@@ -0,0 +1,113 @@
+package api
+
+import (
+	"boshi_exporter/fetchers"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StatusSchemaVersion is the version of the status API documents,
+// it is increased on incompatible changes of the field names or types
+const StatusSchemaVersion = 1
+
+// SnapshotStatus is the latest fetched value of a fetcher with the time it was fetched and the latest fetch error
+type SnapshotStatus[T any] struct {
+	Data      *T         `json:"data"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Status is the document served by GET /api/v1/status
+type Status struct {
+	SchemaVersion int                                   `json:"schema_version"`
+	Instance      SnapshotStatus[fetchers.InstanceSpec] `json:"instance"`
+	Monit         SnapshotStatus[fetchers.MonitStat]    `json:"monit"`
+	System        SnapshotStatus[SystemStatus]          `json:"system"`
+}
+
+// ProcessStatus is the document served by GET /api/v1/status/processes/{name}
+type ProcessStatus struct {
+	SchemaVersion int                          `json:"schema_version"`
+	Name          string                       `json:"name"`
+	FetchedAt     time.Time                    `json:"fetched_at"`
+	Process       *fetchers.MonitProcessStatus `json:"process"`
+}
+
+// ErrorStatus is the document served on errors
+type ErrorStatus struct {
+	SchemaVersion int    `json:"schema_version"`
+	Error         string `json:"error"`
+}
+
+func newSnapshotStatus[T any](snapshot fetchers.Snapshot[T]) SnapshotStatus[T] {
+	status := SnapshotStatus[T]{Data: snapshot.Value}
+	if snapshot.Value != nil {
+		status.FetchedAt = &snapshot.Time
+	}
+	if snapshot.Err != nil {
+		status.Error = snapshot.Err.Error()
+	}
+	return status
+}
+
+// mapSnapshot converts the value of the snapshot into its API document
+func mapSnapshot[T, D any](snapshot fetchers.Snapshot[T], convert func(*T) *D) fetchers.Snapshot[D] {
+	mapped := fetchers.Snapshot[D]{Time: snapshot.Time, Err: snapshot.Err}
+	if snapshot.Value != nil {
+		mapped.Value = convert(snapshot.Value)
+	}
+	return mapped
+}
+
+func newStatus(spec fetchers.Snapshot[fetchers.InstanceSpec], monit fetchers.Snapshot[fetchers.MonitStat], system fetchers.Snapshot[fetchers.SystemStat]) Status {
+	return Status{
+		SchemaVersion: StatusSchemaVersion,
+		Instance:      newSnapshotStatus(spec),
+		Monit:         newSnapshotStatus(monit),
+		System:        newSnapshotStatus(mapSnapshot(system, newSystemStatus)),
+	}
+}
+
+func (a *API) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	writeJSON(w, http.StatusOK, newStatus(a.snapshots.Spec.Get(ctx), a.snapshots.Monit.Get(ctx), a.snapshots.System.Get(ctx)))
+}
+
+func (a *API) handleProcessStatus(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	snapshot := a.snapshots.Monit.Get(r.Context())
+	if snapshot.Value == nil {
+		message := "monit status is not available"
+		if snapshot.Err != nil {
+			message = snapshot.Err.Error()
+		}
+		writeError(w, http.StatusServiceUnavailable, message)
+		return
+	}
+	process, ok := snapshot.Value.Processes[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown process '"+name+"'")
+		return
+	}
+	writeJSON(w, http.StatusOK, ProcessStatus{
+		SchemaVersion: StatusSchemaVersion,
+		Name:          name,
+		FetchedAt:     snapshot.Time,
+		Process:       &process,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorStatus{SchemaVersion: StatusSchemaVersion, Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, document any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		zap.L().Debug("Failed to write API response", zap.Error(err))
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"boshi_exporter/fetchers"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshots(monitErr error) *fetchers.Snapshots {
+	return &fetchers.Snapshots{
+		Spec: fetchers.NewSnapshotSource("spec", func(context.Context) (*fetchers.InstanceSpec, error) {
+			return &fetchers.InstanceSpec{
+				Deployment: "test-dev", Name: "nats", Index: 1, ID: "b36ca4c9", AZ: "z2",
+				Networks: map[string]fetchers.NetworkSpec{"default": {IP: "10.0.1.5", Default: []string{"dns", "gateway"}}},
+			}, nil
+		}),
+		Monit: fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+			if monitErr != nil {
+				return nil, monitErr
+			}
+			return &fetchers.MonitStat{
+				Version: "5.2.5",
+				Uptime:  time.Hour,
+				Processes: map[string]fetchers.MonitProcessStatus{
+					"nats": {Status: "running", MonitoringStatus: "monitored", PID: "42", Uptime: time.Minute},
+				},
+			}, nil
+		}),
+		System: fetchers.NewSnapshotSource("system", func(context.Context) (*fetchers.SystemStat, error) {
+			return &fetchers.SystemStat{CPU: &fetchers.CPUStat{LogicalCores: 4, PhysicalCores: 2}}, nil
+		}),
+	}
+}
+
+func get(t *testing.T, handler http.Handler, path string) (*httptest.ResponseRecorder, map[string]any) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document), recorder.Body.String())
+	return recorder, document
+}
+
+func TestAPI_Status(t *testing.T) {
+	recorder, document := get(t, NewAPI(newTestSnapshots(nil)), "/api/v1/status")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.EqualValues(t, StatusSchemaVersion, document["schema_version"])
+
+	instance := document["instance"].(map[string]any)
+	assert.Equal(t, "test-dev", instance["data"].(map[string]any)["deployment"])
+	assert.NotEmpty(t, instance["fetched_at"])
+	assert.NotContains(t, instance, "error")
+
+	monit := document["monit"].(map[string]any)["data"].(map[string]any)
+	assert.Equal(t, "5.2.5", monit["version"])
+	assert.EqualValues(t, time.Hour, monit["uptime_ns"])
+	nats := monit["processes"].(map[string]any)["nats"].(map[string]any)
+	assert.Equal(t, "running", nats["status"])
+	assert.Equal(t, "42", nats["pid"])
+
+	system := document["system"].(map[string]any)["data"].(map[string]any)
+	assert.EqualValues(t, 4, system["cpu"].(map[string]any)["logical_cores"])
+}
+
+func TestAPI_StatusFetchError(t *testing.T) {
+	recorder, document := get(t, NewAPI(newTestSnapshots(errors.New("monit is down"))), "/api/v1/status")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	monit := document["monit"].(map[string]any)
+	assert.Nil(t, monit["data"])
+	assert.Equal(t, "monit is down", monit["error"])
+}
+
+func TestAPI_ProcessStatus(t *testing.T) {
+	api := NewAPI(newTestSnapshots(nil))
+
+	recorder, document := get(t, api, "/api/v1/status/processes/nats")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "nats", document["name"])
+	assert.Equal(t, "monitored", document["process"].(map[string]any)["monitoring_status"])
+
+	recorder, document = get(t, api, "/api/v1/status/processes/unknown")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "unknown process 'unknown'", document["error"])
+}
+
+func TestAPI_ProcessStatusMonitUnavailable(t *testing.T) {
+	recorder, document := get(t, NewAPI(newTestSnapshots(errors.New("monit is down"))), "/api/v1/status/processes/nats")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "monit is down", document["error"])
+}
+
+func TestStatus_Golden(t *testing.T) {
+	fetchedAt := time.Date(2025, 5, 23, 11, 3, 35, 0, time.UTC)
+	spec := fetchers.Snapshot[fetchers.InstanceSpec]{Time: fetchedAt, Value: &fetchers.InstanceSpec{
+		Deployment: "test-dev", Name: "nats", Index: 1, ID: "b36ca4c9", AZ: "z2",
+		Networks: map[string]fetchers.NetworkSpec{"default": {IP: "10.0.1.5", Default: []string{"dns", "gateway"}}},
+	}}
+	monit := fetchers.Snapshot[fetchers.MonitStat]{Err: errors.New("monit is down")}
+	system := fetchers.Snapshot[fetchers.SystemStat]{Time: fetchedAt, Value: &fetchers.SystemStat{
+		Host: &fetchers.HostStat{Load: &load.AvgStat{Load1: 0.5, Load5: 0.25, Load15: 0.125}},
+		CPU:  &fetchers.CPUStat{LogicalCores: 4, PhysicalCores: 2},
+		Memory: &fetchers.MemoryStat{
+			VM:         &mem.VirtualMemoryStat{Total: 8192, Available: 6144, Used: 2048, Free: 4096, UsedPercent: 25, Cached: 1024},
+			SwapMemory: &mem.SwapMemoryStat{Total: 1024, Used: 256, Free: 768, UsedPercent: 25, Sin: 1},
+		},
+		Disks: &fetchers.DisksStat{
+			RootDisk: &disk.UsageStat{Path: "/", Fstype: "ext4", Total: 4096, Used: 1024, Free: 3072, UsedPercent: 25,
+				InodesTotal: 100, InodesUsed: 10, InodesFree: 90, InodesUsedPercent: 10},
+			DataDisk: &disk.UsageStat{Path: "/var/vcap/data", Fstype: "ext4", Total: 2048, Used: 1024, Free: 1024, UsedPercent: 50},
+		},
+	}}
+
+	actual, err := json.MarshalIndent(newStatus(spec, monit, system), "", "  ")
+	require.NoError(t, err)
+	expected, err := os.ReadFile(filepath.Join("testdata", "status.golden.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"boshi_exporter/fetchers"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// SystemStatus is the system part of the status document, its fields are owned by the API
+// so that dependency updates of the system fetcher do not change the schema
+type SystemStatus struct {
+	Host   *HostStatus   `json:"host"`
+	CPU    *CPUStatus    `json:"cpu"`
+	Memory *MemoryStatus `json:"memory"`
+	Disks  *DisksStatus  `json:"disks"`
+}
+
+type HostStatus struct {
+	Load *LoadStatus `json:"load"`
+}
+
+type LoadStatus struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+type CPUStatus struct {
+	LogicalCores  int `json:"logical_cores"`
+	PhysicalCores int `json:"physical_cores"`
+}
+
+type MemoryStatus struct {
+	VM   *VirtualMemoryStatus `json:"vm"`
+	Swap *SwapMemoryStatus    `json:"swap"`
+}
+
+type VirtualMemoryStatus struct {
+	TotalBytes     uint64  `json:"total_bytes"`
+	AvailableBytes uint64  `json:"available_bytes"`
+	UsedBytes      uint64  `json:"used_bytes"`
+	FreeBytes      uint64  `json:"free_bytes"`
+	UsedPercent    float64 `json:"used_percent"`
+}
+
+type SwapMemoryStatus struct {
+	TotalBytes  uint64  `json:"total_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+type DisksStatus struct {
+	Root  *DiskStatus `json:"root"`
+	Data  *DiskStatus `json:"data"`
+	Store *DiskStatus `json:"store"`
+}
+
+type DiskStatus struct {
+	Path              string  `json:"path"`
+	FSType            string  `json:"fs_type"`
+	TotalBytes        uint64  `json:"total_bytes"`
+	UsedBytes         uint64  `json:"used_bytes"`
+	FreeBytes         uint64  `json:"free_bytes"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+}
+
+func newSystemStatus(stat *fetchers.SystemStat) *SystemStatus {
+	status := &SystemStatus{}
+	if stat.Host != nil {
+		status.Host = &HostStatus{}
+		if load := stat.Host.Load; load != nil {
+			status.Host.Load = &LoadStatus{Load1: load.Load1, Load5: load.Load5, Load15: load.Load15}
+		}
+	}
+	if stat.CPU != nil {
+		status.CPU = &CPUStatus{LogicalCores: stat.CPU.LogicalCores, PhysicalCores: stat.CPU.PhysicalCores}
+	}
+	if stat.Memory != nil {
+		status.Memory = &MemoryStatus{VM: newVirtualMemoryStatus(stat.Memory.VM), Swap: newSwapMemoryStatus(stat.Memory.SwapMemory)}
+	}
+	if stat.Disks != nil {
+		status.Disks = &DisksStatus{
+			Root:  newDiskStatus(stat.Disks.RootDisk),
+			Data:  newDiskStatus(stat.Disks.DataDisk),
+			Store: newDiskStatus(stat.Disks.StoreDisk),
+		}
+	}
+	return status
+}
+
+func newVirtualMemoryStatus(vm *mem.VirtualMemoryStat) *VirtualMemoryStatus {
+	if vm == nil {
+		return nil
+	}
+	return &VirtualMemoryStatus{
+		TotalBytes:     vm.Total,
+		AvailableBytes: vm.Available,
+		UsedBytes:      vm.Used,
+		FreeBytes:      vm.Free,
+		UsedPercent:    vm.UsedPercent,
+	}
+}
+
+func newSwapMemoryStatus(swap *mem.SwapMemoryStat) *SwapMemoryStatus {
+	if swap == nil {
+		return nil
+	}
+	return &SwapMemoryStatus{
+		TotalBytes:  swap.Total,
+		UsedBytes:   swap.Used,
+		FreeBytes:   swap.Free,
+		UsedPercent: swap.UsedPercent,
+	}
+}
+
+func newDiskStatus(usage *disk.UsageStat) *DiskStatus {
+	if usage == nil {
+		return nil
+	}
+	return &DiskStatus{
+		Path:              usage.Path,
+		FSType:            usage.Fstype,
+		TotalBytes:        usage.Total,
+		UsedBytes:         usage.Used,
+		FreeBytes:         usage.Free,
+		UsedPercent:       usage.UsedPercent,
+		InodesTotal:       usage.InodesTotal,
+		InodesUsed:        usage.InodesUsed,
+		InodesFree:        usage.InodesFree,
+		InodesUsedPercent: usage.InodesUsedPercent,
+	}
+}
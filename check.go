@@ -0,0 +1,54 @@
+package main
+
+import (
+	"boshi_exporter/collectors"
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"boshi_exporter/nagios"
+	"context"
+	"fmt"
+	"time"
+)
+
+// runCheck fetches the instance state once and prints the Nagios plugin output, the returned exit code is the plugin state
+func runCheck(cfg *config.Config) int {
+	checkCtx := cfg.CreateCheckContext()
+	ctx, cancel := context.WithTimeout(context.Background(), checkCtx.Timeout)
+	defer cancel()
+
+	certExpiries, err := fetchCertExpiries(ctx, cfg)
+	if err != nil {
+		fmt.Printf("BOSHI %s - %v\n", nagios.Unknown, err)
+		return int(nagios.Unknown)
+	}
+	snapshots, err := createSnapshots(cfg, fetchers.NewFetchers(*cfg.BoshSpecPath, *cfg.MonitPath))
+	if err != nil {
+		fmt.Printf("BOSHI %s - %v\n", nagios.Unknown, err)
+		return int(nagios.Unknown)
+	}
+	// the check is a single scrape, it records or replays one frame
+	ctx = snapshots.Scrape(ctx)
+	result := nagios.Evaluate(checkCtx, snapshots.Monit.Get(ctx), snapshots.System.Get(ctx), certExpiries, time.Now())
+	fmt.Println(result)
+	return int(result.State)
+}
+
+// fetchCertExpiries runs the probes of the collectors config file and returns their certificate expiries
+func fetchCertExpiries(ctx context.Context, cfg *config.Config) (map[string]time.Time, error) {
+	collectorsConfig, err := config.LoadCollectorsConfig(*cfg.CollectorConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(collectorsConfig.Probes) == 0 {
+		return nil, nil
+	}
+	metricsCtx, err := cfg.CreateMetricsContext(collectors.LabelNames)
+	if err != nil {
+		return nil, err
+	}
+	probeCollector, err := collectors.NewProbeCollector(metricsCtx, nil, collectorsConfig.Probes)
+	if err != nil {
+		return nil, err
+	}
+	return probeCollector.CertificateExpiries(ctx), nil
+}
@@ -4,59 +4,180 @@ import (
 	"boshi_exporter/config"
 	"boshi_exporter/fetchers"
 	"context"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	"slices"
+	"sync"
+	"time"
 )
 
+// Names of the metric groups which can be selected with the collect[] and exclude[] scrape parameters
+const (
+	BaseCollectorName     = "base"
+	MonitCollectorName    = "monit"
+	SystemCollectorName   = "system"
+	TextfileCollectorName = "textfile"
+	ExecCollectorName     = "exec"
+	ProbeCollectorName    = "probe"
+	ListenerCollectorName = "listeners"
+)
+
+// CollectorNames lists all metric groups, the groups without metrics in the BoshInstanceCollector
+// are served by separate collectors selected with Enabled
+var CollectorNames = []string{
+	BaseCollectorName, MonitCollectorName, SystemCollectorName,
+	TextfileCollectorName, ExecCollectorName, ProbeCollectorName, ListenerCollectorName,
+}
+
 type BoshInstanceCollector struct {
-	fetchers      *fetchers.Fetchers
-	baseMetrics   *BaseMetrics
-	monitMetrics  *MonitMetrics
-	systemMetrics *SystemMetrics
-	instanceSpec  *fetchers.InstanceSpec
+	snapshots      *fetchers.Snapshots
+	snapshotAge    *prometheus.Desc
+	labels         *labelPolicy
+	baseMetrics    *BaseMetrics
+	monitMetrics   *MonitMetrics
+	systemMetrics  *SystemMetrics
+	instanceSpec   *fetchers.InstanceSpec
+	instanceLabels prometheus.Labels
+	enabled        map[string]bool // nil means all groups are enabled
+
+	// emitMu serializes emitting into the shared metric vectors and collecting them, a concurrent
+	// Emit would reset series another collect is still reading; it is shared with the filtered collectors
+	emitMu *sync.Mutex
 }
 
-func NewBoshInstanceCollector(programName, programVersion string, metricsContext *config.MetricsContext, fetchers *fetchers.Fetchers) (*BoshInstanceCollector, error) {
-	instanceSpec, err := fetchers.SpecFetcher.Fetch(context.Background())
-	if err != nil {
-		return nil, err
+func NewBoshInstanceCollector(programName, programVersion string, metricsContext *config.MetricsContext, snapshots *fetchers.Snapshots) (*BoshInstanceCollector, error) {
+	specSnapshot := snapshots.Spec.Get(context.Background())
+	if specSnapshot.Err != nil {
+		return nil, specSnapshot.Err
 	}
+	instanceSpec := specSnapshot.Value
+	labels := newLabelPolicy(metricsContext)
 	return &BoshInstanceCollector{
-		fetchers:      fetchers,
-		baseMetrics:   NewBaseMetrics(programName, programVersion, metricsContext, instanceSpec),
-		monitMetrics:  NewMonitMetrics(metricsContext, instanceSpec),
-		systemMetrics: NewSystemMetrics(metricsContext, instanceSpec),
-		instanceSpec:  instanceSpec,
+		snapshots: snapshots,
+		snapshotAge: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "collector", "snapshot_age_seconds"),
+			"Age of the fetched data served by the collector (seconds)",
+			labels.names(collectorLabel), *NewInstanceLabels(metricsContext, instanceSpec),
+		),
+		labels:         labels,
+		baseMetrics:    NewBaseMetrics(programName, programVersion, metricsContext, instanceSpec),
+		monitMetrics:   NewMonitMetrics(metricsContext, instanceSpec),
+		systemMetrics:  NewSystemMetrics(metricsContext, instanceSpec),
+		instanceSpec:   instanceSpec,
+		instanceLabels: *NewInstanceLabels(metricsContext, instanceSpec),
+		emitMu:         &sync.Mutex{},
 	}, nil
 }
 
+// InstanceSpec returns the spec of the Bosh instance the collector was created for
+func (b *BoshInstanceCollector) InstanceSpec() *fetchers.InstanceSpec {
+	return b.instanceSpec
+}
+
+// InstanceLabels returns the constant labels of the Bosh instance attached to the metrics
+func (b *BoshInstanceCollector) InstanceLabels() prometheus.Labels {
+	return b.instanceLabels
+}
+
+// Filter returns a collector sharing the metrics of b but limited to the given groups.
+// An empty collect list selects all groups, exclude removes groups from the selection.
+func (b *BoshInstanceCollector) Filter(collect, exclude []string) (*BoshInstanceCollector, error) {
+	enabled := make(map[string]bool)
+	if len(collect) == 0 {
+		for _, name := range CollectorNames {
+			enabled[name] = b.isEnabled(name)
+		}
+	}
+	for _, name := range collect {
+		if !slices.Contains(CollectorNames, name) {
+			return nil, fmt.Errorf("unknown collector '%s', known collectors: %v", name, CollectorNames)
+		}
+		enabled[name] = b.isEnabled(name)
+	}
+	for _, name := range exclude {
+		if !slices.Contains(CollectorNames, name) {
+			return nil, fmt.Errorf("unknown collector '%s', known collectors: %v", name, CollectorNames)
+		}
+		delete(enabled, name)
+	}
+	filtered := *b
+	filtered.enabled = enabled
+	return &filtered, nil
+}
+
 func (b *BoshInstanceCollector) Describe(ch chan<- *prometheus.Desc) {
-	b.describeAllMetrics(b.baseMetrics, ch)
-	b.describeAllMetrics(b.monitMetrics, ch)
-	b.describeAllMetrics(b.systemMetrics, ch)
+	ch <- b.snapshotAge
+	for name, metrics := range b.metricsGroups() {
+		if b.isEnabled(name) {
+			b.describeAllMetrics(metrics, ch)
+		}
+	}
 }
 
 func (b *BoshInstanceCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
-	b.baseMetrics.Emit()
-
-	monitStat, err := b.fetchers.MonitFetcher.Fetch(ctx)
-	if err != nil {
-		zap.L().Error("Failed to fetch monit stat, some monitMetrics won't be updated", zap.Error(err))
-	} else {
-		b.monitMetrics.Emit(monitStat)
+	ctx := b.snapshots.Scrape(context.Background())
+	// fetch before locking, so concurrent scrapes still share a single in-flight fetch
+	var monitSnapshot fetchers.Snapshot[fetchers.MonitStat]
+	if b.isEnabled(MonitCollectorName) {
+		monitSnapshot = b.snapshots.Monit.Get(ctx)
+		if monitSnapshot.Err != nil {
+			zap.L().Error("Failed to fetch monit stat, some monitMetrics won't be updated", zap.Error(monitSnapshot.Err))
+		}
+	}
+	var systemSnapshot fetchers.Snapshot[fetchers.SystemStat]
+	if b.isEnabled(SystemCollectorName) {
+		systemSnapshot = b.snapshots.System.Get(ctx)
+		if systemSnapshot.Err != nil {
+			zap.L().Error("Failed to fetch system stat, some systemMetrics won't be updated", zap.Error(systemSnapshot.Err))
+		}
+	}
+
+	b.emitMu.Lock()
+	defer b.emitMu.Unlock()
+	if b.isEnabled(BaseCollectorName) {
+		b.baseMetrics.Emit()
+		b.collectAllMetrics(b.baseMetrics, ch)
+	}
+
+	if b.isEnabled(MonitCollectorName) {
+		if monitSnapshot.Value != nil {
+			b.monitMetrics.Emit(monitSnapshot.Value)
+			b.collectSnapshotAge(MonitCollectorName, monitSnapshot.Time, ch)
+		}
+		b.collectAllMetrics(b.monitMetrics, ch)
+	}
+
+	if b.isEnabled(SystemCollectorName) {
+		if systemSnapshot.Value != nil {
+			b.systemMetrics.Emit(systemSnapshot.Value)
+			b.collectSnapshotAge(SystemCollectorName, systemSnapshot.Time, ch)
+		}
+		b.collectAllMetrics(b.systemMetrics, ch)
 	}
+}
+
+func (b *BoshInstanceCollector) collectSnapshotAge(name string, fetched time.Time, ch chan<- prometheus.Metric) {
+	age := time.Since(fetched).Seconds()
+	labelValues := b.labels.values([]string{collectorLabel}, name)
+	ch <- prometheus.MustNewConstMetric(b.snapshotAge, prometheus.GaugeValue, age, labelValues...)
+}
 
-	systemStat, err := b.fetchers.SystemFetcher.Fetch(ctx)
-	if err != nil {
-		zap.L().Error("Failed to fetch system stat, some monitMetrics won't be updated", zap.Error(err))
-	} else {
-		b.systemMetrics.Emit(systemStat)
+func (b *BoshInstanceCollector) metricsGroups() map[string]Metrics {
+	return map[string]Metrics{
+		BaseCollectorName:   b.baseMetrics,
+		MonitCollectorName:  b.monitMetrics,
+		SystemCollectorName: b.systemMetrics,
 	}
+}
+
+// Enabled reports whether the metric group is selected by the filter of the collector
+func (b *BoshInstanceCollector) Enabled(name string) bool {
+	return b.isEnabled(name)
+}
 
-	b.collectAllMetrics(b.baseMetrics, ch)
-	b.collectAllMetrics(b.monitMetrics, ch)
-	b.collectAllMetrics(b.systemMetrics, ch)
+func (b *BoshInstanceCollector) isEnabled(name string) bool {
+	return b.enabled == nil || b.enabled[name]
 }
 
 func (b *BoshInstanceCollector) collectAllMetrics(metrics Metrics, ch chan<- prometheus.Metric) {
@@ -0,0 +1,121 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoshInstanceCollector_Filter(t *testing.T) {
+	collector := &BoshInstanceCollector{}
+
+	filtered, err := collector.Filter([]string{MonitCollectorName, SystemCollectorName}, nil)
+	assert.NoError(t, err)
+	assert.False(t, filtered.isEnabled(BaseCollectorName))
+	assert.True(t, filtered.isEnabled(MonitCollectorName))
+	assert.True(t, filtered.isEnabled(SystemCollectorName))
+
+	filtered, err = collector.Filter(nil, []string{MonitCollectorName})
+	assert.NoError(t, err)
+	assert.True(t, filtered.isEnabled(BaseCollectorName))
+	assert.False(t, filtered.isEnabled(MonitCollectorName))
+	assert.True(t, filtered.isEnabled(SystemCollectorName))
+
+	// filters narrow an already filtered collector, they never widen it
+	refiltered, err := filtered.Filter([]string{MonitCollectorName, SystemCollectorName}, nil)
+	assert.NoError(t, err)
+	assert.False(t, refiltered.isEnabled(BaseCollectorName))
+	assert.False(t, refiltered.isEnabled(MonitCollectorName))
+	assert.True(t, refiltered.isEnabled(SystemCollectorName))
+
+	// the original collector is left untouched
+	assert.True(t, collector.isEnabled(MonitCollectorName))
+
+	_, err = collector.Filter([]string{"unknown"}, nil)
+	assert.Error(t, err)
+	_, err = collector.Filter(nil, []string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestBoshInstanceCollector_ConcurrentCollect(t *testing.T) {
+	processes := make(map[string]fetchers.MonitProcessStatus)
+	for i := 0; i < 500; i++ {
+		processes[fmt.Sprintf("process-%d", i)] = fetchers.MonitProcessStatus{Status: "running", MonitoringStatus: "monitored"}
+	}
+	snapshots := &fetchers.Snapshots{
+		Spec: fetchers.NewSnapshotSource("spec", func(context.Context) (*fetchers.InstanceSpec, error) {
+			return &fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats"}, nil
+		}),
+		Monit: fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+			return &fetchers.MonitStat{Version: "5.2.5", Processes: processes}, nil
+		}),
+		System: fetchers.NewSnapshotSource("system", func(context.Context) (*fetchers.SystemStat, error) {
+			return nil, errors.New("not available")
+		}),
+	}
+	collector, err := NewBoshInstanceCollector("boshi_exporter", "test", &config.MetricsContext{Namespace: "boshi", Schemas: []string{config.SchemaV1}}, snapshots)
+	require.NoError(t, err)
+	filtered, err := collector.Filter([]string{MonitCollectorName}, nil)
+	require.NoError(t, err)
+
+	// every gather must see all processes, even while other gathers re-emit the shared vectors
+	var wg sync.WaitGroup
+	for _, c := range []prometheus.Collector{collector, filtered, collector, filtered} {
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(c))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				count, err := testutil.GatherAndCount(registry, "boshi_monit_process_status_info")
+				assert.NoError(t, err)
+				assert.Equal(t, len(processes), count)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBoshInstanceCollector_RecordsFramePerScrape(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the system fetcher is recorded from /proc")
+	}
+	tmp := t.TempDir()
+	specPath := filepath.Join(tmp, "spec.json")
+	require.NoError(t, os.WriteFile(specPath, []byte(`{"deployment": "test-dev", "name": "nats", "index": 1}`), 0o644))
+	monitPath := filepath.Join(tmp, "monit")
+	require.NoError(t, os.WriteFile(monitPath, []byte("#!/bin/sh\necho 'The Monit daemon 5.2.5 uptime: 1m'\n"), 0o755))
+	recordingDir := filepath.Join(tmp, "recording")
+	recorder, err := fetchers.NewRecorder(recordingDir, 0)
+	require.NoError(t, err)
+	snapshots := fetchers.NewRecordingSnapshots(fetchers.NewFetchers(specPath, monitPath), recorder)
+
+	collector, err := NewBoshInstanceCollector("boshi_exporter", "test", &config.MetricsContext{Namespace: "boshi"}, snapshots)
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+	const scrapes = 3
+	for range scrapes {
+		_, err := registry.Gather()
+		require.NoError(t, err)
+		// the fetches of the alerts, events and API do not consume frames
+		snapshots.Monit.Get(context.Background())
+	}
+	for _, source := range []string{"spec", "monit", "system"} {
+		frames, err := os.ReadDir(filepath.Join(recordingDir, source))
+		require.NoError(t, err)
+		assert.Len(t, frames, scrapes, source)
+	}
+}
@@ -0,0 +1,179 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+const (
+	execLabel    = "exec"
+	execKeyLabel = "key"
+)
+
+// ExecCollector runs the configured commands on every scrape and exposes their exit code,
+// duration and parsed output as <namespace>_exec_* metrics
+type ExecCollector struct {
+	execs          []config.ExecConfig
+	namespace      string
+	labels         *labelPolicy
+	instanceLabels prometheus.Labels
+	success        *prometheus.Desc
+	exitCode       *prometheus.Desc
+	duration       *prometheus.Desc
+	value          *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*ExecCollector)(nil)
+
+func NewExecCollector(metricsContext *config.MetricsContext, instanceLabels prometheus.Labels, execs []config.ExecConfig) *ExecCollector {
+	labels := newLabelPolicy(metricsContext)
+	desc := func(name, help string, labelNames ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "exec", name), help,
+			labels.names(labelNames...), instanceLabels,
+		)
+	}
+	return &ExecCollector{
+		execs:          execs,
+		namespace:      metricsContext.Namespace,
+		labels:         labels,
+		instanceLabels: instanceLabels,
+		success:        desc("success", "Whether the command exited with code 0 and its output was parsed (1=success)", execLabel),
+		exitCode:       desc("exit_code", "Exit code of the command, -1 if it did not exit", execLabel),
+		duration:       desc("duration_seconds", "Run time of the command (seconds)", execLabel),
+		value:          desc("value", "Value parsed from the command output", execLabel, execKeyLabel),
+	}
+}
+
+// Describe sends no descriptors, the metrics of the prometheus parser are not known in advance
+func (e *ExecCollector) Describe(_ chan<- *prometheus.Desc) {
+}
+
+func (e *ExecCollector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for i := range e.execs {
+		wg.Add(1)
+		go func(exec *config.ExecConfig) {
+			defer wg.Done()
+			e.collectExec(exec, ch)
+		}(&e.execs[i])
+	}
+	wg.Wait()
+}
+
+func (e *ExecCollector) collectExec(exec *config.ExecConfig, ch chan<- prometheus.Metric) {
+	labelValues := e.labels.values([]string{execLabel}, exec.Name)
+	result, err := fetchers.RunCommand(context.Background(), exec.Timeout, exec.Command, exec.Args...)
+	if result == nil {
+		zap.L().Error("Failed to run exec collector", zap.String("exec", exec.Name), zap.Error(err))
+		ch <- prometheus.MustNewConstMetric(e.success, prometheus.GaugeValue, 0, labelValues...)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(e.exitCode, prometheus.GaugeValue, float64(result.ExitCode), labelValues...)
+	ch <- prometheus.MustNewConstMetric(e.duration, prometheus.GaugeValue, result.Duration.Seconds(), labelValues...)
+	if err == nil && exec.Parser != config.ExecParserExitCode {
+		err = e.collectOutput(exec, result.Output, ch)
+	}
+	success := 1.0
+	if err != nil {
+		zap.L().Warn("Exec collector failed", zap.String("exec", exec.Name), zap.Error(err))
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(e.success, prometheus.GaugeValue, success, labelValues...)
+}
+
+// collectOutput parses the command output, nothing is collected if the output is invalid
+func (e *ExecCollector) collectOutput(exec *config.ExecConfig, output []byte, ch chan<- prometheus.Metric) error {
+	switch exec.Parser {
+	case config.ExecParserValue:
+		value, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse value of exec '%s', error: %v", exec.Name, err)
+		}
+		ch <- prometheus.MustNewConstMetric(e.value, prometheus.GaugeValue, value, e.labels.values([]string{execLabel, execKeyLabel}, exec.Name, "value")...)
+	case config.ExecParserKeyValue:
+		values, err := parseKeyValues(output)
+		if err != nil {
+			return fmt.Errorf("cannot parse key=value output of exec '%s', error: %v", exec.Name, err)
+		}
+		for _, pair := range values {
+			ch <- prometheus.MustNewConstMetric(e.value, prometheus.GaugeValue, pair.value, e.labels.values([]string{execLabel, execKeyLabel}, exec.Name, pair.key)...)
+		}
+	case config.ExecParserPrometheus:
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(bytes.NewReader(output))
+		if err != nil {
+			return fmt.Errorf("cannot parse prometheus output of exec '%s', error: %v", exec.Name, err)
+		}
+		labels := func(metric *dto.Metric) prometheus.Labels {
+			metricLabels := make(prometheus.Labels, len(metric.GetLabel())+len(e.instanceLabels)+1)
+			for name, value := range e.instanceLabels {
+				metricLabels[name] = value
+			}
+			for _, pair := range metric.GetLabel() {
+				metricLabels[pair.GetName()] = pair.GetValue()
+			}
+			for name, value := range e.labels.labels(prometheus.Labels{execLabel: exec.Name}) {
+				metricLabels[name] = value
+			}
+			return metricLabels
+		}
+		for _, name := range sortedFamilyNames(families) {
+			fqName := prometheus.BuildFQName(e.namespace, "exec", name)
+			for _, m := range newFamilyMetrics(fqName, "Metric read from the exec output", families[name], labels) {
+				ch <- m
+			}
+		}
+	}
+	return nil
+}
+
+type keyValue struct {
+	key   string
+	value float64
+}
+
+// parseKeyValues parses key=value lines, empty lines and lines starting with # are skipped.
+// Keys become label values, so they must be unique, non-empty and valid UTF-8.
+func parseKeyValues(output []byte) ([]keyValue, error) {
+	var values []keyValue
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line '%s' is not key=value", line)
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line '%s' has no numeric value", line)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || !utf8.ValidString(key) {
+			return nil, fmt.Errorf("line '%s' has an invalid key", line)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("key '%s' is repeated", key)
+		}
+		seen[key] = true
+		values = append(values, keyValue{key: key, value: parsed})
+	}
+	return values, scanner.Err()
+}
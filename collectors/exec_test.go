@@ -0,0 +1,55 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecCollector(t *testing.T) {
+	execs := []config.ExecConfig{
+		{Name: "pg_isready", Command: "sh", Args: []string{"-c", "exit 2"}, Timeout: time.Second, Parser: config.ExecParserExitCode},
+		{Name: "lag", Command: "echo", Args: []string{"1.5"}, Timeout: time.Second, Parser: config.ExecParserValue},
+		{Name: "backup", Command: "printf", Args: []string{"# comment\nage=30\nsize = 2048\n"}, Timeout: time.Second, Parser: config.ExecParserKeyValue},
+		{Name: "duplicate", Command: "printf", Args: []string{"lag=1\nlag=2\n"}, Timeout: time.Second, Parser: config.ExecParserKeyValue},
+		{Name: "broken", Command: "echo", Args: []string{"not a number"}, Timeout: time.Second, Parser: config.ExecParserValue},
+		{Name: "replication", Command: "printf", Args: []string{"# TYPE lag_seconds gauge\nlag_seconds{replica=\"1\"} 3\n"}, Timeout: time.Second, Parser: config.ExecParserPrometheus},
+	}
+	collector := NewExecCollector(&config.MetricsContext{Namespace: "boshi"}, prometheus.Labels{"bosh_deployment": "test-dev"}, execs)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	expected := `
+# HELP boshi_exec_exit_code Exit code of the command, -1 if it did not exit
+# TYPE boshi_exec_exit_code gauge
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="backup"} 0
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="broken"} 0
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="duplicate"} 0
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="lag"} 0
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="pg_isready"} 2
+boshi_exec_exit_code{bosh_deployment="test-dev",exec="replication"} 0
+# HELP boshi_exec_lag_seconds Metric read from the exec output
+# TYPE boshi_exec_lag_seconds gauge
+boshi_exec_lag_seconds{bosh_deployment="test-dev",exec="replication",replica="1"} 3
+# HELP boshi_exec_success Whether the command exited with code 0 and its output was parsed (1=success)
+# TYPE boshi_exec_success gauge
+boshi_exec_success{bosh_deployment="test-dev",exec="backup"} 1
+boshi_exec_success{bosh_deployment="test-dev",exec="broken"} 0
+boshi_exec_success{bosh_deployment="test-dev",exec="duplicate"} 0
+boshi_exec_success{bosh_deployment="test-dev",exec="lag"} 1
+boshi_exec_success{bosh_deployment="test-dev",exec="pg_isready"} 0
+boshi_exec_success{bosh_deployment="test-dev",exec="replication"} 1
+# HELP boshi_exec_value Value parsed from the command output
+# TYPE boshi_exec_value gauge
+boshi_exec_value{bosh_deployment="test-dev",exec="backup",key="age"} 30
+boshi_exec_value{bosh_deployment="test-dev",exec="backup",key="size"} 2048
+boshi_exec_value{bosh_deployment="test-dev",exec="lag",key="value"} 1.5
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"boshi_exec_exit_code", "boshi_exec_lag_seconds", "boshi_exec_success", "boshi_exec_value"))
+}
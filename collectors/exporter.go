@@ -0,0 +1,45 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ExporterCollector exposes metrics about the work done by the exporter itself
+type ExporterCollector struct {
+	monitFetcher       *fetchers.MonitFetcher
+	monitExecs         *prometheus.Desc
+	monitParseDuration *prometheus.Desc
+	monitOutputBytes   *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*ExporterCollector)(nil)
+
+func NewExporterCollector(metricsContext *config.MetricsContext, monitFetcher *fetchers.MonitFetcher) *ExporterCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "exporter", name), help,
+			nil, *newLabelPolicy(metricsContext).constLabels(prometheus.Labels{}),
+		)
+	}
+	return &ExporterCollector{
+		monitFetcher:       monitFetcher,
+		monitExecs:         desc("monit_execs_total", "Number of Monit program executions (fork/exec)"),
+		monitParseDuration: desc("monit_parse_duration_seconds", "Duration of the last Monit output parsing (seconds)"),
+		monitOutputBytes:   desc("monit_output_bytes", "Size of the last parsed Monit output in bytes"),
+	}
+}
+
+func (e *ExporterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.monitExecs
+	ch <- e.monitParseDuration
+	ch <- e.monitOutputBytes
+}
+
+func (e *ExporterCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := e.monitFetcher.Stats()
+	ch <- prometheus.MustNewConstMetric(e.monitExecs, prometheus.CounterValue, float64(stats.ExecCount))
+	ch <- prometheus.MustNewConstMetric(e.monitParseDuration, prometheus.GaugeValue, stats.ParseDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(e.monitOutputBytes, prometheus.GaugeValue, float64(stats.OutputBytes))
+}
@@ -0,0 +1,68 @@
+package collectors
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+// newFamilyMetrics converts the metrics of a parsed family into const metrics named name,
+// defaultHelp is used for families without help and labels returns the labels of each metric
+func newFamilyMetrics(name, defaultHelp string, family *dto.MetricFamily, labels func(*dto.Metric) prometheus.Labels) []prometheus.Metric {
+	help := family.GetHelp()
+	if help == "" {
+		help = defaultHelp
+	}
+	var metrics []prometheus.Metric
+	for _, metric := range family.GetMetric() {
+		metricLabels := labels(metric)
+		names := make([]string, 0, len(metricLabels))
+		for label := range metricLabels {
+			names = append(names, label)
+		}
+		sort.Strings(names)
+		values := make([]string, len(names))
+		for i, label := range names {
+			values[i] = metricLabels[label]
+		}
+		desc := prometheus.NewDesc(name, help, names, nil)
+
+		var m prometheus.Metric
+		var err error
+		switch family.GetType() {
+		case dto.MetricType_GAUGE:
+			m, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, metric.GetGauge().GetValue(), values...)
+		case dto.MetricType_COUNTER:
+			m, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, metric.GetCounter().GetValue(), values...)
+		case dto.MetricType_UNTYPED:
+			m, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, metric.GetUntyped().GetValue(), values...)
+		case dto.MetricType_SUMMARY:
+			quantiles := make(map[float64]float64, len(metric.GetSummary().GetQuantile()))
+			for _, q := range metric.GetSummary().GetQuantile() {
+				quantiles[q.GetQuantile()] = q.GetValue()
+			}
+			m, err = prometheus.NewConstSummary(desc, metric.GetSummary().GetSampleCount(), metric.GetSummary().GetSampleSum(), quantiles, values...)
+		case dto.MetricType_HISTOGRAM:
+			buckets := make(map[float64]uint64, len(metric.GetHistogram().GetBucket()))
+			for _, b := range metric.GetHistogram().GetBucket() {
+				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+			}
+			m, err = prometheus.NewConstHistogram(desc, metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum(), buckets, values...)
+		default:
+			err = fmt.Errorf("unsupported metric type %s", family.GetType())
+		}
+		if err != nil {
+			zap.L().Warn("Skipping parsed metric", zap.String("metric", name), zap.Error(err))
+			continue
+		}
+		if metric.TimestampMs != nil {
+			m = prometheus.NewMetricWithTimestamp(time.UnixMilli(metric.GetTimestampMs()), m)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
@@ -0,0 +1,86 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelPolicy drops, renames and adds labels, it is applied to the constant and variable labels of all metrics
+type labelPolicy struct {
+	drop   map[string]bool
+	rename map[string]string
+	extra  map[string]string
+}
+
+func newLabelPolicy(metricsContext *config.MetricsContext) *labelPolicy {
+	policy := &labelPolicy{
+		drop:   make(map[string]bool),
+		rename: metricsContext.RenameLabels,
+		extra:  metricsContext.ExtraLabels,
+	}
+	for _, name := range metricsContext.DropLabels {
+		policy.drop[name] = true
+	}
+	if metricsContext.ProcessPidAsGauge {
+		// PIDs change on every restart, they are exposed as gauge values instead
+		policy.drop[monitProcessPidLabel] = true
+		policy.drop[monitProcessParentPidLabel] = true
+	}
+	return policy
+}
+
+// VolatileLabelNames returns the exposed names of the labels changing on every process restart or state change,
+// they do not identify a series across restarts and failures
+func VolatileLabelNames(metricsContext *config.MetricsContext) []string {
+	return newLabelPolicy(metricsContext).names(monitProcessPidLabel, monitProcessParentPidLabel, monitMonitoringStatusLabel, monitServiceStatusLabel)
+}
+
+// name returns the exposed name of the label
+func (p *labelPolicy) name(name string) string {
+	if renamed, ok := p.rename[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// names returns the exposed names of variable labels, dropped labels are omitted
+func (p *labelPolicy) names(names ...string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if !p.drop[name] {
+			result = append(result, p.name(name))
+		}
+	}
+	return result
+}
+
+// values returns the values of variable labels in the order of names, values of dropped labels are omitted
+func (p *labelPolicy) values(names []string, values ...string) []string {
+	result := make([]string, 0, len(values))
+	for i, name := range names {
+		if !p.drop[name] {
+			result = append(result, values[i])
+		}
+	}
+	return result
+}
+
+// labels returns variable labels keyed by their exposed names, dropped labels are omitted
+func (p *labelPolicy) labels(labels prometheus.Labels) prometheus.Labels {
+	result := make(prometheus.Labels, len(labels))
+	for name, value := range labels {
+		if !p.drop[name] {
+			result[p.name(name)] = value
+		}
+	}
+	return result
+}
+
+// constLabels returns constant labels keyed by their exposed names with the extra labels added
+func (p *labelPolicy) constLabels(labels prometheus.Labels) *prometheus.Labels {
+	result := p.labels(labels)
+	for name, value := range p.extra {
+		result[name] = value
+	}
+	return &result
+}
@@ -0,0 +1,80 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelPolicy_InstanceLabels(t *testing.T) {
+	metricsContext := &config.MetricsContext{
+		Environment:  "test",
+		DropLabels:   []string{instanceIdLabel, directorUuidLabel},
+		RenameLabels: map[string]string{instanceAzLabel: "zone"},
+		ExtraLabels:  map[string]string{"team": "platform"},
+	}
+	spec := &fetchers.InstanceSpec{Deployment: "test-dev", Name: "exporters", Index: 1, ID: "b36ca4c9", AZ: "z2"}
+
+	labels := *NewInstanceLabels(metricsContext, spec)
+	assert.NotContains(t, labels, instanceIdLabel)
+	assert.NotContains(t, labels, directorUuidLabel)
+	assert.NotContains(t, labels, instanceAzLabel)
+	assert.Equal(t, "z2", labels["zone"])
+	assert.Equal(t, "platform", labels["team"])
+	assert.Equal(t, "1", labels[instanceIndexLabel])
+}
+
+func TestLabelPolicy_GroupingLabels(t *testing.T) {
+	metricsContext := &config.MetricsContext{
+		DropLabels:   []string{instanceIndexLabel},
+		RenameLabels: map[string]string{instanceNameLabel: "bosh_job"},
+		ExtraLabels:  map[string]string{"team": "platform"},
+	}
+	spec := &fetchers.InstanceSpec{Deployment: "test-dev", Name: "exporters", Index: 1, ID: "b36ca4c9", AZ: "z2"}
+
+	assert.Equal(t, prometheus.Labels{deploymentLabel: "test-dev", "bosh_job": "exporters"}, NewGroupingLabels(metricsContext, spec))
+}
+
+func TestLabelPolicy_VariableLabels(t *testing.T) {
+	policy := newLabelPolicy(&config.MetricsContext{
+		DropLabels:   []string{monitServiceStatusLabel},
+		RenameLabels: map[string]string{monitProcessNameLabel: "job"},
+	})
+	names := []string{monitProcessNameLabel, monitServiceStatusLabel, monitMonitoringStatusLabel}
+	assert.Equal(t, []string{"job", monitMonitoringStatusLabel}, policy.names(names...))
+	assert.Equal(t, []string{"nats", "monitored"}, policy.values(names, "nats", "running", "monitored"))
+	assert.Equal(t, prometheus.Labels{"job": "nats", monitMonitoringStatusLabel: "monitored"}, policy.labels(prometheus.Labels{
+		monitProcessNameLabel:      "nats",
+		monitServiceStatusLabel:    "running",
+		monitMonitoringStatusLabel: "monitored",
+	}))
+}
+
+func TestLabelPolicy_ProcessPidAsGauge(t *testing.T) {
+	metricsContext := &config.MetricsContext{Namespace: "boshi", Schemas: []string{config.SchemaV2}, ProcessPidAsGauge: true}
+	spec := &fetchers.InstanceSpec{Deployment: "test-dev", Name: "exporters"}
+	metrics := NewMonitMetrics(metricsContext, spec)
+	metrics.Emit(&fetchers.MonitStat{
+		Version: "5.2.5",
+		Processes: map[string]fetchers.MonitProcessStatus{
+			"nats":    {Status: "running", MonitoringStatus: "monitored", PID: "22394", ParentPID: "1"},
+			"stopped": {Status: "not monitored", MonitoringStatus: "not monitored"},
+		},
+	})
+
+	assert.Equal(t, 22394.0, testutil.ToFloat64(metrics.ProcPid.With(prometheus.Labels{monitProcessNameLabel: "nats"})))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProcParentPid.With(prometheus.Labels{monitProcessNameLabel: "nats"})))
+	// processes without a PID don't get a PID gauge
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.ProcPid))
+	// the info metric is keyed by the process name and statuses only
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.ProcStatusInfo))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProcStatusInfo.With(prometheus.Labels{
+		monitProcessNameLabel:      "nats",
+		monitMonitoringStatusLabel: "monitored",
+		monitServiceStatusLabel:    "running",
+	})))
+}
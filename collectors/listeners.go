@@ -0,0 +1,129 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	listenerProtoLabel = "proto"
+	listenerPortLabel  = "port"
+)
+
+// ListenerCollector exposes the sockets the Monit processes are listening on, it catches processes
+// reported as running by Monit which do not accept connections (yet)
+type ListenerCollector struct {
+	monit     *fetchers.SnapshotSource[fetchers.MonitStat]
+	fetcher   *fetchers.ListenerFetcher
+	expected  map[string][]config.ExpectedListener // by process name
+	labels    *labelPolicy
+	listening *prometheus.Desc
+	missing   *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*ListenerCollector)(nil)
+
+func NewListenerCollector(metricsContext *config.MetricsContext, instanceLabels prometheus.Labels, monit *fetchers.SnapshotSource[fetchers.MonitStat], listeners []config.ListenerConfig) *ListenerCollector {
+	labels := newLabelPolicy(metricsContext)
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "process", name), help,
+			labels.names(monitProcessNameLabel, listenerProtoLabel, listenerPortLabel), instanceLabels,
+		)
+	}
+	expected := make(map[string][]config.ExpectedListener, len(listeners))
+	for _, listener := range listeners {
+		expected[listener.Process] = listener.Expected
+	}
+	return &ListenerCollector{
+		monit:     monit,
+		fetcher:   fetchers.NewListenerFetcher(),
+		expected:  expected,
+		labels:    labels,
+		listening: desc("listening", "Socket the Monit process is listening on, the port label holds the path of unix sockets"),
+		missing:   desc("listener_missing", "Whether the expected socket of the Monit process is not listening (1=missing)"),
+	}
+}
+
+func (c *ListenerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.listening
+	ch <- c.missing
+}
+
+func (c *ListenerCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.monit.Get(context.Background())
+	if snapshot.Value == nil {
+		zap.L().Error("Failed to fetch monit stat, listeners won't be collected", zap.Error(snapshot.Err))
+		return
+	}
+	listeners, unreadable, err := c.fetchListeners(snapshot.Value)
+	if err != nil {
+		zap.L().Warn("Failed to read process listeners", zap.Error(err))
+		return
+	}
+	names := []string{monitProcessNameLabel, listenerProtoLabel, listenerPortLabel}
+	for process, processListeners := range listeners {
+		for _, listener := range processListeners {
+			ch <- prometheus.MustNewConstMetric(c.listening, prometheus.GaugeValue, 1,
+				c.labels.values(names, process, listener.Proto, listener.Port)...)
+		}
+	}
+	for process, expected := range c.expected {
+		if unreadable[process] {
+			// the listeners of the process are unknown, they are not reported as missing
+			continue
+		}
+		for _, e := range expected {
+			missing := 1.0
+			if slices.ContainsFunc(listeners[process], func(l fetchers.Listener) bool { return listenerMatches(e, l) }) {
+				missing = 0
+			}
+			ch <- prometheus.MustNewConstMetric(c.missing, prometheus.GaugeValue, missing,
+				c.labels.values(names, process, e.Proto, e.Port)...)
+		}
+	}
+}
+
+// fetchListeners returns the listeners of the running Monit processes by process name
+// and the names of the processes whose listeners cannot be read
+func (c *ListenerCollector) fetchListeners(stat *fetchers.MonitStat) (map[string][]fetchers.Listener, map[string]bool, error) {
+	pids := make(map[string]string, len(stat.Processes))
+	for name, process := range stat.Processes {
+		if pid, err := strconv.Atoi(process.PID); err == nil && pid > 0 {
+			pids[name] = process.PID
+		}
+	}
+	byPid, errs, err := c.fetcher.Fetch(slices.Collect(maps.Values(pids)))
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make(map[string][]fetchers.Listener, len(pids))
+	unreadable := make(map[string]bool)
+	for name, pid := range pids {
+		if err, ok := errs[pid]; ok {
+			zap.L().Warn("Failed to read process listeners", zap.String("process", name), zap.Error(err))
+			unreadable[name] = true
+			continue
+		}
+		result[name] = byPid[pid]
+	}
+	return result, unreadable, nil
+}
+
+// listenerMatches returns whether the listener is the expected one, the tcp proto matches IPv4 and IPv6 sockets
+func listenerMatches(expected config.ExpectedListener, listener fetchers.Listener) bool {
+	if expected.Port != listener.Port {
+		return false
+	}
+	if expected.Proto == fetchers.ListenerProtoTCP {
+		return listener.Proto == fetchers.ListenerProtoTCP || listener.Proto == fetchers.ListenerProtoTCP6
+	}
+	return expected.Proto == listener.Proto
+}
@@ -0,0 +1,61 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerCollector(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the listeners are read from /proc")
+	}
+	tcpListener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = tcpListener.Close() }()
+	port := strconv.Itoa(tcpListener.Addr().(*net.TCPAddr).Port)
+	socketPath := filepath.Join(t.TempDir(), "nats.sock")
+	unixListener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer func() { _ = unixListener.Close() }()
+
+	monit := fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+		return &fetchers.MonitStat{Processes: map[string]fetchers.MonitProcessStatus{
+			"nats":    {Status: "running", PID: strconv.Itoa(os.Getpid())},
+			"stopped": {Status: "not monitored", PID: "-"},
+		}}, nil
+	})
+	listeners := []config.ListenerConfig{
+		{Process: "nats", Expected: []config.ExpectedListener{{Proto: "tcp", Port: port}, {Proto: "unix", Port: "/missing.sock"}}},
+		{Process: "stopped", Expected: []config.ExpectedListener{{Proto: "tcp", Port: "8080"}}},
+	}
+	collector := NewListenerCollector(&config.MetricsContext{Namespace: "boshi"}, prometheus.Labels{"bosh_deployment": "test-dev"}, monit, listeners)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	expected := fmt.Sprintf(`
+# HELP boshi_process_listener_missing Whether the expected socket of the Monit process is not listening (1=missing)
+# TYPE boshi_process_listener_missing gauge
+boshi_process_listener_missing{bosh_deployment="test-dev",port="%[1]s",process_name="nats",proto="tcp"} 0
+boshi_process_listener_missing{bosh_deployment="test-dev",port="/missing.sock",process_name="nats",proto="unix"} 1
+boshi_process_listener_missing{bosh_deployment="test-dev",port="8080",process_name="stopped",proto="tcp"} 1
+# HELP boshi_process_listening Socket the Monit process is listening on, the port label holds the path of unix sockets
+# TYPE boshi_process_listening gauge
+boshi_process_listening{bosh_deployment="test-dev",port="%[1]s",process_name="nats",proto="tcp"} 1
+boshi_process_listening{bosh_deployment="test-dev",port="%[2]s",process_name="nats",proto="unix"} 1
+`, port, socketPath)
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"boshi_process_listener_missing", "boshi_process_listening"))
+}
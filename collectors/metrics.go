@@ -17,7 +17,7 @@ func ListMetricsCollectors(m Metrics) []prometheus.Collector {
 	val := reflect.ValueOf(m).Elem()
 	for i := 0; i < val.NumField(); i++ {
 		fieldVal := val.Field(i)
-		if fieldVal.Kind() == reflect.Ptr && !fieldVal.IsNil() {
+		if fieldVal.Kind() == reflect.Ptr && !fieldVal.IsNil() && fieldVal.CanInterface() {
 			if collector, ok := fieldVal.Interface().(prometheus.Collector); ok {
 				collectors = append(collectors, collector)
 			}
@@ -27,11 +27,11 @@ func ListMetricsCollectors(m Metrics) []prometheus.Collector {
 }
 
 const (
-	namespace = "boshi"
-
 	programNameLabel    = "program_name"
 	programVersionLabel = "program_version"
 
+	collectorLabel = "collector"
+
 	environmentLabel   = "environment"
 	directorNameLabel  = "bosh_name"
 	directorUuidLabel  = "bosh_uuid"
@@ -42,8 +42,54 @@ const (
 	instanceAzLabel    = "bosh_instance_az"
 )
 
+// LabelNames lists the labels attached by the collectors, the label policy of the metrics context is validated against them
+var LabelNames = []string{
+	programNameLabel, programVersionLabel, collectorLabel,
+	environmentLabel, directorNameLabel, directorUuidLabel,
+	deploymentLabel, instanceNameLabel, instanceIdLabel, instanceIndexLabel, instanceAzLabel,
+	monitVersionLabel, monitMonitoringStatusLabel, monitServiceStatusLabel,
+	monitProcessNameLabel, monitProcessPidLabel, monitProcessParentPidLabel, monitCPUModeLabel,
+	textfileLabel, execLabel, execKeyLabel, probePhaseLabel, listenerProtoLabel, listenerPortLabel,
+}
+
+// newSchemaGaugeVec creates the gauge vector only if its naming schema is enabled,
+// nil vectors are skipped by ListMetricsCollectors, setGauge and resetGauges
+func newSchemaGaugeVec(metricsContext *config.MetricsContext, schema string, opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
+	if !metricsContext.HasSchema(schema) {
+		return nil
+	}
+	return prometheus.NewGaugeVec(opts, labelNames)
+}
+
+// setGauge sets the gauge value if the vector exists
+func setGauge(vec *prometheus.GaugeVec, labels prometheus.Labels, value float64) {
+	if vec != nil {
+		vec.With(labels).Set(value)
+	}
+}
+
+// resetGauges deletes all metrics of the existing vectors
+func resetGauges(vecs ...*prometheus.GaugeVec) {
+	for _, vec := range vecs {
+		if vec != nil {
+			vec.Reset()
+		}
+	}
+}
+
+// NewGroupingLabels returns the labels identifying the Bosh instance, its deployment, name and index,
+// with the label policy of the metrics context applied
+func NewGroupingLabels(metricsContext *config.MetricsContext, spec *fetchers.InstanceSpec) prometheus.Labels {
+	return newLabelPolicy(metricsContext).labels(prometheus.Labels{
+		deploymentLabel:    spec.Deployment,
+		instanceNameLabel:  spec.Name,
+		instanceIndexLabel: strconv.Itoa(spec.Index),
+	})
+}
+
+// NewInstanceLabels returns the constant labels of the Bosh instance with the label policy of the metrics context applied
 func NewInstanceLabels(metricsContext *config.MetricsContext, spec *fetchers.InstanceSpec) *prometheus.Labels {
-	return &prometheus.Labels{
+	return newLabelPolicy(metricsContext).constLabels(prometheus.Labels{
 		environmentLabel:   metricsContext.Environment,
 		directorNameLabel:  metricsContext.BoshName,
 		directorUuidLabel:  metricsContext.BoshUuid,
@@ -52,5 +98,5 @@ func NewInstanceLabels(metricsContext *config.MetricsContext, spec *fetchers.Ins
 		instanceIdLabel:    spec.ID,
 		instanceIndexLabel: strconv.Itoa(spec.Index),
 		instanceAzLabel:    spec.AZ,
-	}
+	})
 }
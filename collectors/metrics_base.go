@@ -4,7 +4,6 @@ import (
 	"boshi_exporter/config"
 	"boshi_exporter/fetchers"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type BaseMetrics struct {
@@ -17,21 +16,21 @@ var _ Metrics = (*BaseMetrics)(nil)
 func NewBaseMetrics(programName, programVersion string, metricsContext *config.MetricsContext, spec *fetchers.InstanceSpec) *BaseMetrics {
 	opts := func(name, help string, constantLabels *prometheus.Labels) prometheus.GaugeOpts {
 		return prometheus.GaugeOpts{
-			Namespace:   namespace,
+			Namespace:   metricsContext.Namespace,
 			Name:        name,
 			Help:        help,
 			ConstLabels: *constantLabels,
 		}
 	}
 	return &BaseMetrics{
-		BuildInfo: promauto.NewGaugeVec(
-			opts("build_info", "Program build information", &prometheus.Labels{
+		BuildInfo: prometheus.NewGaugeVec(
+			opts("build_info", "Program build information", newLabelPolicy(metricsContext).constLabels(prometheus.Labels{
 				programNameLabel:    programName,
 				programVersionLabel: programVersion,
-			}),
+			})),
 			[]string{},
 		),
-		InstanceInfo: promauto.NewGaugeVec(
+		InstanceInfo: prometheus.NewGaugeVec(
 			opts("instance_info", "Bosh instance information", NewInstanceLabels(metricsContext, spec)),
 			[]string{}),
 	}
@@ -4,7 +4,6 @@ import (
 	"boshi_exporter/config"
 	"boshi_exporter/fetchers"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type SystemMetrics struct {
@@ -46,7 +45,7 @@ var _ Metrics = (*SystemMetrics)(nil)
 func NewSystemMetrics(metricsContext *config.MetricsContext, spec *fetchers.InstanceSpec) *SystemMetrics {
 	opts := func(name, help string) prometheus.GaugeOpts {
 		return prometheus.GaugeOpts{
-			Namespace:   namespace,
+			Namespace:   metricsContext.Namespace,
 			Subsystem:   "system",
 			Name:        name,
 			Help:        help,
@@ -55,33 +54,33 @@ func NewSystemMetrics(metricsContext *config.MetricsContext, spec *fetchers.Inst
 	}
 	return &SystemMetrics{
 
-		Load1:  promauto.NewGaugeVec(opts("load1", "1-minute load average"), []string{}),
-		Load5:  promauto.NewGaugeVec(opts("load5", "5-minute load average"), []string{}),
-		Load15: promauto.NewGaugeVec(opts("load15", "15-minute load average"), []string{}),
+		Load1:  prometheus.NewGaugeVec(opts("load1", "1-minute load average"), []string{}),
+		Load5:  prometheus.NewGaugeVec(opts("load5", "5-minute load average"), []string{}),
+		Load15: prometheus.NewGaugeVec(opts("load15", "15-minute load average"), []string{}),
 
-		CpuLogicalCoreCount:  promauto.NewGaugeVec(opts("cpu_logical_core_count", "Number of logical CPU cores"), []string{}),
-		CpuPhysicalCoreCount: promauto.NewGaugeVec(opts("cpu_physical_core_count", "Number of physical CPU cores"), []string{}),
+		CpuLogicalCoreCount:  prometheus.NewGaugeVec(opts("cpu_logical_core_count", "Number of logical CPU cores"), []string{}),
+		CpuPhysicalCoreCount: prometheus.NewGaugeVec(opts("cpu_physical_core_count", "Number of physical CPU cores"), []string{}),
 
-		VmSize:       promauto.NewGaugeVec(opts("memory_virtual_size_bytes", "Total virtual memory in bytes"), []string{}),
-		VmAvailable:  promauto.NewGaugeVec(opts("memory_virtual_available_bytes", "Available virtual memory in bytes"), []string{}),
-		VmUsed:       promauto.NewGaugeVec(opts("memory_virtual_used_bytes", "Used virtual memory in bytes"), []string{}),
-		VmUsageRatio: promauto.NewGaugeVec(opts("memory_virtual_usage_ratio", "Used virtual memory fraction (1=100%)"), []string{}),
+		VmSize:       prometheus.NewGaugeVec(opts("memory_virtual_size_bytes", "Total virtual memory in bytes"), []string{}),
+		VmAvailable:  prometheus.NewGaugeVec(opts("memory_virtual_available_bytes", "Available virtual memory in bytes"), []string{}),
+		VmUsed:       prometheus.NewGaugeVec(opts("memory_virtual_used_bytes", "Used virtual memory in bytes"), []string{}),
+		VmUsageRatio: prometheus.NewGaugeVec(opts("memory_virtual_usage_ratio", "Used virtual memory fraction (1=100%)"), []string{}),
 
-		SwapSize:       promauto.NewGaugeVec(opts("memory_swap_size_bytes", "Total swap memory in bytes"), []string{}),
-		SwapUsed:       promauto.NewGaugeVec(opts("memory_swap_used_bytes", "Used swap memory in bytes"), []string{}),
-		SwapUsageRatio: promauto.NewGaugeVec(opts("memory_swap_usage_ratio", "Used swap memory fraction (1=100%)"), []string{}),
+		SwapSize:       prometheus.NewGaugeVec(opts("memory_swap_size_bytes", "Total swap memory in bytes"), []string{}),
+		SwapUsed:       prometheus.NewGaugeVec(opts("memory_swap_used_bytes", "Used swap memory in bytes"), []string{}),
+		SwapUsageRatio: prometheus.NewGaugeVec(opts("memory_swap_usage_ratio", "Used swap memory fraction (1=100%)"), []string{}),
 
-		DiskRootSize:       promauto.NewGaugeVec(opts("disk_root_size_bytes", "Total bytes on root filesystem"), []string{}),
-		DiskRootUsed:       promauto.NewGaugeVec(opts("disk_root_used_bytes", "Used bytes on root filesystem"), []string{}),
-		DiskRootUsageRatio: promauto.NewGaugeVec(opts("disk_root_usage_ratio", "Used root filesystem fraction (1=100%)"), []string{}),
+		DiskRootSize:       prometheus.NewGaugeVec(opts("disk_root_size_bytes", "Total bytes on root filesystem"), []string{}),
+		DiskRootUsed:       prometheus.NewGaugeVec(opts("disk_root_used_bytes", "Used bytes on root filesystem"), []string{}),
+		DiskRootUsageRatio: prometheus.NewGaugeVec(opts("disk_root_usage_ratio", "Used root filesystem fraction (1=100%)"), []string{}),
 
-		DiskDataSize:       promauto.NewGaugeVec(opts("disk_data_size_bytes", "Total bytes on /var/vcap/data"), []string{}),
-		DiskDataUsed:       promauto.NewGaugeVec(opts("disk_data_used_bytes", "Used bytes on /var/vcap/data"), []string{}),
-		DiskDataUsageRatio: promauto.NewGaugeVec(opts("disk_data_usage_ratio", "Used /var/vcap/data fraction (1=100%)"), []string{}),
+		DiskDataSize:       prometheus.NewGaugeVec(opts("disk_data_size_bytes", "Total bytes on /var/vcap/data"), []string{}),
+		DiskDataUsed:       prometheus.NewGaugeVec(opts("disk_data_used_bytes", "Used bytes on /var/vcap/data"), []string{}),
+		DiskDataUsageRatio: prometheus.NewGaugeVec(opts("disk_data_usage_ratio", "Used /var/vcap/data fraction (1=100%)"), []string{}),
 
-		DiskStoreSize:       promauto.NewGaugeVec(opts("disk_store_size_bytes", "Total bytes on /var/vcap/store"), []string{}),
-		DiskStoreUsed:       promauto.NewGaugeVec(opts("disk_store_used_bytes", "Used bytes on /var/vcap/store"), []string{}),
-		DiskStoreUsageRatio: promauto.NewGaugeVec(opts("disk_store_usage_ratio", "Used /var/vcap/store fraction (1=100%)"), []string{}),
+		DiskStoreSize:       prometheus.NewGaugeVec(opts("disk_store_size_bytes", "Total bytes on /var/vcap/store"), []string{}),
+		DiskStoreUsed:       prometheus.NewGaugeVec(opts("disk_store_used_bytes", "Used bytes on /var/vcap/store"), []string{}),
+		DiskStoreUsageRatio: prometheus.NewGaugeVec(opts("disk_store_usage_ratio", "Used /var/vcap/store fraction (1=100%)"), []string{}),
 	}
 }
 
@@ -49,3 +49,58 @@ func TestMetrics_ListMetricsCollectors(t *testing.T) {
 		t.Errorf("expected system collectors, got '%v'", list)
 	}
 }
+
+func TestMetrics_NamespaceAndSchemas(t *testing.T) {
+	spec := &fetchers.InstanceSpec{Deployment: "test-dev", Name: "exporters", Index: 0, ID: "b36ca4c9", AZ: "z2"}
+	stat := &fetchers.MonitStat{
+		Version: "5.2.5",
+		Processes: map[string]fetchers.MonitProcessStatus{
+			"boshi_exporter": {Status: "running", MonitoringStatus: "monitored", PID: "65763", ParentPID: "1", MemoryUsedBytesTotal: 1024},
+		},
+	}
+	gatherNames := func(metricsContext *config.MetricsContext) map[string]bool {
+		metrics := NewMonitMetrics(metricsContext, spec)
+		metrics.Emit(stat)
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(metrics.Collectors()...)
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("Gather() returned error: %v", err)
+		}
+		names := make(map[string]bool)
+		for _, family := range families {
+			names[family.GetName()] = true
+		}
+		return names
+	}
+
+	names := gatherNames(&config.MetricsContext{Namespace: "custom", Schemas: []string{config.SchemaV1}})
+	if !names["custom_monit_process_memory_used_bytes_total"] {
+		t.Errorf("expected v1 metric in custom namespace, got '%v'", names)
+	}
+	if names["custom_monit_process_tree_memory_used_bytes"] {
+		t.Errorf("unexpected v2 metric, got '%v'", names)
+	}
+
+	names = gatherNames(&config.MetricsContext{Namespace: "boshi", Schemas: []string{config.SchemaV2}})
+	if !names["boshi_monit_process_tree_memory_used_bytes"] {
+		t.Errorf("expected v2 metric, got '%v'", names)
+	}
+	if names["boshi_monit_process_memory_used_bytes_total"] {
+		t.Errorf("unexpected v1 metric, got '%v'", names)
+	}
+
+	if !names["boshi_monit_process_children_count"] {
+		t.Errorf("expected metric of both schemas, got '%v'", names)
+	}
+
+	names = gatherNames(&config.MetricsContext{Namespace: "boshi"})
+	if !names["boshi_monit_process_memory_used_bytes_total"] || names["boshi_monit_process_tree_memory_used_bytes"] {
+		t.Errorf("expected v1 metrics without schema, got '%v'", names)
+	}
+
+	names = gatherNames(&config.MetricsContext{Namespace: "boshi", Schemas: []string{config.SchemaV1, config.SchemaV2}})
+	if !names["boshi_monit_process_memory_used_bytes_total"] || !names["boshi_monit_process_tree_memory_used_bytes"] {
+		t.Errorf("expected metrics of both schemas, got '%v'", names)
+	}
+}
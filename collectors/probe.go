@@ -0,0 +1,233 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	probePhaseLabel = "phase"
+
+	// probeMaxBodyBytes limits the response body matched by the body regex
+	probeMaxBodyBytes = 1 << 20
+)
+
+// probe is a configured HTTP check with its prepared client
+type probe struct {
+	config    config.ProbeConfig
+	client    *http.Client
+	bodyRegex *regexp.Regexp
+}
+
+// ProbeCollector checks the local HTTP endpoints of the Monit processes on every scrape,
+// the metrics are labelled by process_name to join with the Monit process metrics
+type ProbeCollector struct {
+	probes       []*probe
+	labels       *labelPolicy
+	success      *prometheus.Desc
+	statusCode   *prometheus.Desc
+	duration     *prometheus.Desc
+	phases       *prometheus.Desc
+	certNotAfter *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*ProbeCollector)(nil)
+
+func NewProbeCollector(metricsContext *config.MetricsContext, instanceLabels prometheus.Labels, probeConfigs []config.ProbeConfig) (*ProbeCollector, error) {
+	labels := newLabelPolicy(metricsContext)
+	desc := func(name, help string, labelNames ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "probe", name), help,
+			labels.names(labelNames...), instanceLabels,
+		)
+	}
+	collector := &ProbeCollector{
+		labels:       labels,
+		success:      desc("success", "Whether the probe returned the expected status and body (1=success)", monitProcessNameLabel),
+		statusCode:   desc("http_status_code", "HTTP status code of the probe response", monitProcessNameLabel),
+		duration:     desc("duration_seconds", "Duration of the probe (seconds)", monitProcessNameLabel),
+		phases:       desc("http_duration_seconds", "Duration of the probe phases connect, tls and first_byte (seconds)", monitProcessNameLabel, probePhaseLabel),
+		certNotAfter: desc("ssl_earliest_cert_expiry_seconds", "Earliest expiry of the server certificate chain as Unix timestamp (seconds)", monitProcessNameLabel),
+	}
+	for _, probeConfig := range probeConfigs {
+		p := &probe{config: probeConfig}
+		client, err := config.NewHTTPClient(probeConfig.TLS, probeConfig.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create TLS config of probe '%s', error: %v", probeConfig.Process, err)
+		}
+		// every probe measures a fresh connection
+		client.Transport.(*http.Transport).DisableKeepAlives = true
+		p.client = client
+		if probeConfig.BodyRegex != "" {
+			re, err := regexp.Compile(probeConfig.BodyRegex)
+			if err != nil {
+				return nil, fmt.Errorf("cannot compile body regex of probe '%s', error: %v", probeConfig.Process, err)
+			}
+			p.bodyRegex = re
+		}
+		collector.probes = append(collector.probes, p)
+	}
+	return collector, nil
+}
+
+func (c *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.success
+	ch <- c.statusCode
+	ch <- c.duration
+	ch <- c.phases
+	ch <- c.certNotAfter
+}
+
+func (c *ProbeCollector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	for _, p := range c.probes {
+		wg.Add(1)
+		go func(p *probe) {
+			defer wg.Done()
+			c.collectProbe(p, ch)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// CertificateExpiries runs the probes and returns the earliest certificate expiry of the TLS endpoints by process name
+func (c *ProbeCollector) CertificateExpiries(ctx context.Context) map[string]time.Time {
+	expiries := make(map[string]time.Time)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range c.probes {
+		wg.Add(1)
+		go func(p *probe) {
+			defer wg.Done()
+			result, _ := p.run(ctx)
+			result.mu.Lock()
+			defer result.mu.Unlock()
+			if !result.certNotAfter.IsZero() {
+				mu.Lock()
+				expiries[p.config.Process] = result.certNotAfter
+				mu.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+	return expiries
+}
+
+// probeResult holds the measurements of a single probe, the trace callbacks may run on the dialer goroutines
+type probeResult struct {
+	mu           sync.Mutex
+	statusCode   int
+	duration     time.Duration
+	phases       map[string]time.Duration
+	certNotAfter time.Time
+}
+
+func (c *ProbeCollector) collectProbe(p *probe, ch chan<- prometheus.Metric) {
+	result, err := p.run(context.Background())
+	result.mu.Lock()
+	defer result.mu.Unlock()
+	labelValues := c.labels.values([]string{monitProcessNameLabel}, p.config.Process)
+	success := 1.0
+	if err != nil {
+		zap.L().Debug("Probe failed", zap.String("process", p.config.Process), zap.String("url", p.config.URL), zap.Error(err))
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, success, labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, result.duration.Seconds(), labelValues...)
+	if result.statusCode > 0 {
+		ch <- prometheus.MustNewConstMetric(c.statusCode, prometheus.GaugeValue, float64(result.statusCode), labelValues...)
+	}
+	for _, phase := range []string{"connect", "tls", "first_byte"} {
+		if d, ok := result.phases[phase]; ok {
+			phaseValues := c.labels.values([]string{monitProcessNameLabel, probePhaseLabel}, p.config.Process, phase)
+			ch <- prometheus.MustNewConstMetric(c.phases, prometheus.GaugeValue, d.Seconds(), phaseValues...)
+		}
+	}
+	if !result.certNotAfter.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.certNotAfter, prometheus.GaugeValue, float64(result.certNotAfter.Unix()), labelValues...)
+	}
+}
+
+// run requests the probe URL and checks the response, the result holds the measurements taken until a failure
+func (p *probe) run(ctx context.Context) (*probeResult, error) {
+	result := &probeResult{phases: make(map[string]time.Duration)}
+	start := time.Now()
+	defer func() {
+		result.mu.Lock()
+		result.duration = time.Since(start)
+		result.mu.Unlock()
+	}()
+
+	var connectStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(_, _ string) {
+			result.mu.Lock()
+			defer result.mu.Unlock()
+			connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			result.mu.Lock()
+			defer result.mu.Unlock()
+			if err == nil {
+				result.phases["connect"] = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			result.mu.Lock()
+			defer result.mu.Unlock()
+			result.phases["tls"] = time.Since(tlsStart)
+			for _, cert := range state.PeerCertificates {
+				if result.certNotAfter.IsZero() || cert.NotAfter.Before(result.certNotAfter) {
+					result.certNotAfter = cert.NotAfter
+				}
+			}
+		},
+		GotFirstResponseByte: func() {
+			result.mu.Lock()
+			defer result.mu.Unlock()
+			result.phases["first_byte"] = time.Since(start)
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, p.config.URL, nil)
+	if err != nil {
+		return result, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	result.mu.Lock()
+	result.statusCode = resp.StatusCode
+	result.mu.Unlock()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, probeMaxBodyBytes))
+	if err != nil {
+		return result, err
+	}
+
+	if p.config.ExpectedStatus != 0 && resp.StatusCode != p.config.ExpectedStatus {
+		return result, fmt.Errorf("unexpected HTTP status %d, expected %d", resp.StatusCode, p.config.ExpectedStatus)
+	}
+	if p.config.ExpectedStatus == 0 && resp.StatusCode/100 != 2 {
+		return result, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+	if p.bodyRegex != nil && !p.bodyRegex.Match(body) {
+		return result, fmt.Errorf("body does not match '%s'", p.config.BodyRegex)
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeCollector(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"status":"ok"}`)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	probes := []config.ProbeConfig{
+		{Process: "api", URL: server.URL + "/health", BodyRegex: `"status":"ok"`, Timeout: time.Second},
+		{Process: "worker", URL: server.URL + "/unavailable", Timeout: time.Second},
+		{Process: "nginx", URL: server.URL + "/health", BodyRegex: `"status":"failed"`, Timeout: time.Second},
+		{Process: "maintenance", URL: server.URL + "/unavailable", ExpectedStatus: http.StatusServiceUnavailable, Timeout: time.Second},
+		{Process: "uaa", URL: tlsServer.URL + "/health", Timeout: time.Second, TLS: &config.TLSContext{InsecureSkipVerify: true}},
+	}
+	collector, err := NewProbeCollector(&config.MetricsContext{Namespace: "boshi"}, prometheus.Labels{"bosh_deployment": "test-dev"}, probes)
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	expected := `
+# HELP boshi_probe_http_status_code HTTP status code of the probe response
+# TYPE boshi_probe_http_status_code gauge
+boshi_probe_http_status_code{bosh_deployment="test-dev",process_name="api"} 200
+boshi_probe_http_status_code{bosh_deployment="test-dev",process_name="maintenance"} 503
+boshi_probe_http_status_code{bosh_deployment="test-dev",process_name="nginx"} 200
+boshi_probe_http_status_code{bosh_deployment="test-dev",process_name="uaa"} 200
+boshi_probe_http_status_code{bosh_deployment="test-dev",process_name="worker"} 503
+# HELP boshi_probe_success Whether the probe returned the expected status and body (1=success)
+# TYPE boshi_probe_success gauge
+boshi_probe_success{bosh_deployment="test-dev",process_name="api"} 1
+boshi_probe_success{bosh_deployment="test-dev",process_name="maintenance"} 1
+boshi_probe_success{bosh_deployment="test-dev",process_name="nginx"} 0
+boshi_probe_success{bosh_deployment="test-dev",process_name="uaa"} 1
+boshi_probe_success{bosh_deployment="test-dev",process_name="worker"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"boshi_probe_http_status_code", "boshi_probe_success"))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	phases := make(map[string]bool)
+	var certExpiry float64
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if family.GetName() == "boshi_probe_http_duration_seconds" && labelValue(metric, "process_name") == "uaa" {
+				phases[labelValue(metric, "phase")] = true
+			}
+			if family.GetName() == "boshi_probe_ssl_earliest_cert_expiry_seconds" {
+				require.Equal(t, "uaa", labelValue(metric, "process_name"))
+				certExpiry = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	require.Equal(t, map[string]bool{"connect": true, "tls": true, "first_byte": true}, phases)
+	require.Equal(t, float64(tlsServer.Certificate().NotAfter.Unix()), certExpiry)
+}
+
+func TestProbeCollectorInvalidRegex(t *testing.T) {
+	_, err := NewProbeCollector(&config.MetricsContext{Namespace: "boshi"}, nil, []config.ProbeConfig{
+		{Process: "api", URL: "http://127.0.0.1/", BodyRegex: "("},
+	})
+	require.Error(t, err)
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+	return ""
+}
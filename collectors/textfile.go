@@ -0,0 +1,206 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+const textfileLabel = "file"
+
+// descNameRegexp extracts the metric name from the string of a descriptor, descriptors have no accessor for it
+var descNameRegexp = regexp.MustCompile(`^Desc\{fqName: "([^"]*)"`)
+
+// TextfileCollector exposes the metrics of the *.prom files in a directory written by the Bosh jobs.
+// The instance labels are added to the file metrics, files which cannot be parsed or conflict with
+// metrics of other files or of the exporter are skipped and reported by the parse error metric.
+type TextfileCollector struct {
+	directory      string
+	namespace      string
+	reserved       map[string]bool // names of the metrics of the other collectors outside the namespace
+	labels         *labelPolicy
+	instanceLabels prometheus.Labels
+	mtime          *prometheus.Desc
+	parseError     *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*TextfileCollector)(nil)
+
+// NewTextfileCollector creates the collector, the metrics described by the reserved collectors
+// (e.g. the go_ and process_ self metrics) are rejected like the metrics in the exporter namespace
+func NewTextfileCollector(metricsContext *config.MetricsContext, instanceLabels prometheus.Labels, directory string, reserved []prometheus.Collector) *TextfileCollector {
+	labels := newLabelPolicy(metricsContext)
+	return &TextfileCollector{
+		directory:      directory,
+		namespace:      metricsContext.Namespace,
+		reserved:       describedNames(reserved),
+		labels:         labels,
+		instanceLabels: instanceLabels,
+		mtime: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "textfile", "mtime_seconds"),
+			"Modification time of the textfile as Unix timestamp (seconds)",
+			labels.names(textfileLabel), instanceLabels,
+		),
+		parseError: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsContext.Namespace, "textfile", "parse_error"),
+			"Whether the textfile could not be parsed or merged (1=error)",
+			labels.names(textfileLabel), instanceLabels,
+		),
+	}
+}
+
+// Describe sends no descriptors, the file metrics are not known in advance which makes the collector unchecked
+func (t *TextfileCollector) Describe(_ chan<- *prometheus.Desc) {
+}
+
+func (t *TextfileCollector) Collect(ch chan<- prometheus.Metric) {
+	paths, err := filepath.Glob(filepath.Join(t.directory, "*.prom"))
+	if err != nil {
+		zap.L().Error("Failed to list textfiles", zap.String("directory", t.directory), zap.Error(err))
+		return
+	}
+	sort.Strings(paths)
+	merged := make(map[string]*dto.MetricFamily)
+	series := make(map[string]bool)
+	for _, path := range paths {
+		file := filepath.Base(path)
+		info, families, err := t.readFile(path)
+		if err == nil {
+			err = t.merge(merged, series, families)
+		}
+		parseError := 0.0
+		if err != nil {
+			zap.L().Warn("Skipping textfile", zap.String("file", path), zap.Error(err))
+			parseError = 1
+		}
+		ch <- prometheus.MustNewConstMetric(t.parseError, prometheus.GaugeValue, parseError, t.labels.values([]string{textfileLabel}, file)...)
+		if info != nil {
+			ch <- prometheus.MustNewConstMetric(t.mtime, prometheus.GaugeValue, float64(info.ModTime().UnixNano())/1e9, t.labels.values([]string{textfileLabel}, file)...)
+		}
+	}
+	for _, name := range sortedFamilyNames(merged) {
+		t.collectFamily(merged[name], ch)
+	}
+}
+
+func (t *TextfileCollector) readFile(path string) (os.FileInfo, map[string]*dto.MetricFamily, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open textfile '%s', error: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot stat textfile '%s', error: %v", path, err)
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(f)
+	if err != nil {
+		return info, nil, fmt.Errorf("cannot parse textfile '%s', error: %v", path, err)
+	}
+	return info, families, nil
+}
+
+// merge adds the families of a file to the merged families, the file is rejected as a whole when it uses
+// the exporter namespace or a reserved metric name, changes the type of a family or repeats a series
+func (t *TextfileCollector) merge(merged map[string]*dto.MetricFamily, series map[string]bool, families map[string]*dto.MetricFamily) error {
+	fileSeries := make(map[string]bool)
+	for name, family := range families {
+		if strings.HasPrefix(name, t.namespace+"_") {
+			return fmt.Errorf("metric '%s' uses the reserved namespace '%s'", name, t.namespace)
+		}
+		if t.reserved[name] {
+			return fmt.Errorf("metric '%s' collides with a metric of the exporter", name)
+		}
+		if existing, ok := merged[name]; ok && existing.GetType() != family.GetType() {
+			return fmt.Errorf("metric '%s' has type %s but another textfile has type %s", name, family.GetType(), existing.GetType())
+		}
+		for _, metric := range family.GetMetric() {
+			key := seriesKey(name, t.metricLabels(metric))
+			if series[key] || fileSeries[key] {
+				return fmt.Errorf("metric '%s' has a duplicate series", name)
+			}
+			fileSeries[key] = true
+		}
+	}
+	for name, family := range families {
+		if existing, ok := merged[name]; ok {
+			existing.Metric = append(existing.Metric, family.GetMetric()...)
+		} else {
+			merged[name] = family
+		}
+	}
+	for key := range fileSeries {
+		series[key] = true
+	}
+	return nil
+}
+
+// metricLabels returns the labels of the file metric with the instance labels added,
+// labels set by the file take precedence
+func (t *TextfileCollector) metricLabels(metric *dto.Metric) prometheus.Labels {
+	labels := make(prometheus.Labels, len(metric.GetLabel())+len(t.instanceLabels))
+	for name, value := range t.instanceLabels {
+		labels[name] = value
+	}
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+func (t *TextfileCollector) collectFamily(family *dto.MetricFamily, ch chan<- prometheus.Metric) {
+	for _, m := range newFamilyMetrics(family.GetName(), "Metric read from a textfile", family, t.metricLabels) {
+		ch <- m
+	}
+}
+
+// describedNames returns the names of the metrics described by the collectors
+func describedNames(collectors []prometheus.Collector) map[string]bool {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		for _, c := range collectors {
+			c.Describe(descs)
+		}
+		close(descs)
+	}()
+	names := make(map[string]bool)
+	for desc := range descs {
+		if match := descNameRegexp.FindStringSubmatch(desc.String()); match != nil {
+			names[match[1]] = true
+		}
+	}
+	return names
+}
+
+func seriesKey(name string, labels prometheus.Labels) string {
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	key.WriteString(name)
+	for _, label := range names {
+		key.WriteString("\xff" + label + "\xff" + labels[label])
+	}
+	return key.String()
+}
+
+func sortedFamilyNames(families map[string]*dto.MetricFamily) []string {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
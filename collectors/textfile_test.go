@@ -0,0 +1,69 @@
+package collectors
+
+import (
+	"boshi_exporter/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTextfile(t *testing.T, dir, name, content string, mtime time.Time) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+func TestTextfileCollector(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Unix(1700000000, 0)
+	writeTextfile(t, dir, "backup.prom", `# HELP backup_last_success_timestamp_seconds Last successful backup
+# TYPE backup_last_success_timestamp_seconds gauge
+backup_last_success_timestamp_seconds{database="ccdb"} 1.7e+09
+`, mtime)
+	writeTextfile(t, dir, "migration.prom", `# TYPE migration_version untyped
+migration_version{bosh_deployment="override"} 42
+`, mtime)
+	writeTextfile(t, dir, "malformed.prom", "backup_last_success_timestamp_seconds{database=\"ccdb\" 1\n", mtime)
+	writeTextfile(t, dir, "reserved.prom", "boshi_monit_uptime_seconds 1\n", mtime)
+	writeTextfile(t, dir, "z-duplicate.prom", `backup_last_success_timestamp_seconds{database="ccdb"} 1
+`, mtime)
+	writeTextfile(t, dir, "self.prom", "go_goroutines 3\n", mtime)
+	writeTextfile(t, dir, "ignored.txt", "ignored_metric 1\n", mtime)
+
+	self := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines", Help: "Number of goroutines that currently exist."})
+	collector := NewTextfileCollector(&config.MetricsContext{Namespace: "boshi"}, prometheus.Labels{"bosh_deployment": "test-dev"}, dir, []prometheus.Collector{self})
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	expected := `
+# HELP backup_last_success_timestamp_seconds Last successful backup
+# TYPE backup_last_success_timestamp_seconds gauge
+backup_last_success_timestamp_seconds{bosh_deployment="test-dev",database="ccdb"} 1.7e+09
+# HELP boshi_textfile_mtime_seconds Modification time of the textfile as Unix timestamp (seconds)
+# TYPE boshi_textfile_mtime_seconds gauge
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="backup.prom"} 1.7e+09
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="malformed.prom"} 1.7e+09
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="migration.prom"} 1.7e+09
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="reserved.prom"} 1.7e+09
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="self.prom"} 1.7e+09
+boshi_textfile_mtime_seconds{bosh_deployment="test-dev",file="z-duplicate.prom"} 1.7e+09
+# HELP boshi_textfile_parse_error Whether the textfile could not be parsed or merged (1=error)
+# TYPE boshi_textfile_parse_error gauge
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="backup.prom"} 0
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="malformed.prom"} 1
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="migration.prom"} 0
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="reserved.prom"} 1
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="self.prom"} 1
+boshi_textfile_parse_error{bosh_deployment="test-dev",file="z-duplicate.prom"} 1
+# HELP migration_version Metric read from a textfile
+# TYPE migration_version untyped
+migration_version{bosh_deployment="override"} 42
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output parsers of the exec collectors
+const (
+	ExecParserExitCode   = "exit_code"
+	ExecParserValue      = "value"
+	ExecParserKeyValue   = "key_value"
+	ExecParserPrometheus = "prometheus"
+)
+
+const (
+	defaultExecTimeout  = 10 * time.Second
+	defaultProbeTimeout = 10 * time.Second
+)
+
+// CollectorsConfig is the YAML document of the --collector.config-file
+type CollectorsConfig struct {
+	Exec      []ExecConfig     `yaml:"exec"`
+	Probes    []ProbeConfig    `yaml:"probes"`
+	Listeners []ListenerConfig `yaml:"listeners"`
+}
+
+// ExecConfig defines a command run on every scrape, its exit code and parsed output are exposed as metrics
+type ExecConfig struct {
+	Name    string        `yaml:"name"`
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"` // default: 10s
+	Parser  string        `yaml:"parser"`  // exit_code (default), value, key_value or prometheus
+}
+
+// ProbeConfig defines an HTTP health check of the local endpoint of a Monit process
+type ProbeConfig struct {
+	Process        string        `yaml:"process"` // Monit process name
+	URL            string        `yaml:"url"`
+	ExpectedStatus int           `yaml:"expected_status"` // default: any 2xx status
+	BodyRegex      string        `yaml:"body_regex"`
+	Timeout        time.Duration `yaml:"timeout"` // default: 10s
+	TLS            *TLSContext   `yaml:"tls"`
+}
+
+// ListenerConfig defines the sockets a Monit process is expected to listen on
+type ListenerConfig struct {
+	Process  string             `yaml:"process"` // Monit process name
+	Expected []ExpectedListener `yaml:"expected"`
+}
+
+// ExpectedListener is a listening socket, the tcp proto matches IPv4 and IPv6 sockets
+type ExpectedListener struct {
+	Proto string `yaml:"proto"` // tcp, tcp6 or unix
+	Port  string `yaml:"port"`  // TCP port or unix socket path
+}
+
+// LoadCollectorsConfig reads the collectors config file, an empty path returns an empty config
+func LoadCollectorsConfig(path string) (*CollectorsConfig, error) {
+	if path == "" {
+		return &CollectorsConfig{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read collectors config file '%s', error: %v", path, err)
+	}
+	collectorsConfig, err := ParseCollectorsConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse collectors config file '%s', error: %v", path, err)
+	}
+	return collectorsConfig, nil
+}
+
+// ParseCollectorsConfig parses and validates the YAML collectors config, defaults are applied
+func ParseCollectorsConfig(data []byte) (*CollectorsConfig, error) {
+	var collectorsConfig CollectorsConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&collectorsConfig); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i := range collectorsConfig.Exec {
+		exec := &collectorsConfig.Exec[i]
+		if exec.Name == "" || exec.Command == "" {
+			return nil, fmt.Errorf("exec collector '%s' must have a name and a command", exec.Name)
+		}
+		if names[exec.Name] {
+			return nil, fmt.Errorf("exec collector '%s' is defined more than once", exec.Name)
+		}
+		names[exec.Name] = true
+		if exec.Timeout <= 0 {
+			exec.Timeout = defaultExecTimeout
+		}
+		switch exec.Parser {
+		case "":
+			exec.Parser = ExecParserExitCode
+		case ExecParserExitCode, ExecParserValue, ExecParserKeyValue, ExecParserPrometheus:
+		default:
+			return nil, fmt.Errorf("exec collector '%s' has an unknown parser '%s'", exec.Name, exec.Parser)
+		}
+	}
+	processes := make(map[string]bool)
+	for i := range collectorsConfig.Probes {
+		probe := &collectorsConfig.Probes[i]
+		if probe.Process == "" || probe.URL == "" {
+			return nil, fmt.Errorf("probe '%s' must have a process and a url", probe.Process)
+		}
+		if processes[probe.Process] {
+			return nil, fmt.Errorf("probe of process '%s' is defined more than once", probe.Process)
+		}
+		processes[probe.Process] = true
+		if probe.Timeout <= 0 {
+			probe.Timeout = defaultProbeTimeout
+		}
+	}
+	processes = make(map[string]bool)
+	for _, listener := range collectorsConfig.Listeners {
+		if listener.Process == "" {
+			return nil, fmt.Errorf("listeners must have a process")
+		}
+		if processes[listener.Process] {
+			return nil, fmt.Errorf("listeners of process '%s' are defined more than once", listener.Process)
+		}
+		processes[listener.Process] = true
+		expectedListeners := make(map[ExpectedListener]bool)
+		for _, expected := range listener.Expected {
+			switch expected.Proto {
+			case "tcp", "tcp6", "unix":
+			default:
+				return nil, fmt.Errorf("expected listener of process '%s' has an unknown proto '%s'", listener.Process, expected.Proto)
+			}
+			if expected.Port == "" {
+				return nil, fmt.Errorf("expected %s listener of process '%s' must have a port", expected.Proto, listener.Process)
+			}
+			if expectedListeners[expected] {
+				return nil, fmt.Errorf("expected %s listener '%s' of process '%s' is defined more than once", expected.Proto, expected.Port, listener.Process)
+			}
+			expectedListeners[expected] = true
+		}
+	}
+	return &collectorsConfig, nil
+}
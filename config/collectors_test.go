@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCollectorsConfig_Listeners(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "valid",
+			config: `listeners:
+  - process: nats
+    expected: [{proto: tcp, port: "4222"}, {proto: tcp6, port: "4222"}, {proto: unix, port: /var/vcap/sys/run/nats/nats.sock}]`,
+		},
+		{
+			name: "unknown proto",
+			config: `listeners:
+  - process: nats
+    expected: [{proto: udp, port: "4222"}]`,
+			expected: "expected listener of process 'nats' has an unknown proto 'udp'",
+		},
+		{
+			name: "duplicate listener",
+			config: `listeners:
+  - process: nats
+    expected: [{proto: tcp, port: "4222"}, {proto: tcp, port: "4222"}]`,
+			expected: "expected tcp listener '4222' of process 'nats' is defined more than once",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseCollectorsConfig([]byte(test.config))
+			if test.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
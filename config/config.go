@@ -1,21 +1,173 @@
 package config
 
 import (
+	"fmt"
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Metrics naming schemas, v2 renames the process tree gauges of v1 whose _total suffix is reserved
+// for counters, e.g. process_memory_used_bytes_total becomes process_tree_memory_used_bytes.
+// All other metrics are emitted in both schemas, v1 is kept for existing dashboards.
+const (
+	SchemaV1 = "v1"
+	SchemaV2 = "v2"
+)
+
+var metricsSchemas = []string{SchemaV1, SchemaV2}
+
+// labelNameRegexp matches valid Prometheus label names
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Subcommands of the exporter
+const (
+	ServeCommand    = "serve"
+	CheckCommand    = "check"
+	DumpCommand     = "dump"
+	SimulateCommand = "simulate"
+)
+
+// Output formats of the dump subcommand
+const (
+	DumpFormatText        = "text"
+	DumpFormatOpenMetrics = "openmetrics"
+	DumpFormatJSON        = "json"
+)
+
+// Scenarios of the simulate subcommand
+const (
+	ScenarioCrashloop = "crashloop"
+	ScenarioDiskFill  = "disk-fill"
+	ScenarioSwapStorm = "swap-storm"
 )
 
 type Config struct {
-	ListenAddress      *string
-	TelemetryPath      *string
-	BoshSpecPath       *string
-	MonitPath          *string
-	MetricsNamespace   *string
-	MetricsEnvironment *string
-	MetricsBoshName    *string
-	MetricsBoshUuid    *string
-	LogLevel           *string
-	LogPath            *string
+	Command string // selected subcommand
+
+	ListenAddress       *string
+	TelemetryPath       *string
+	ReadyProcessInclude *string
+	ReadyProcessExclude *string
+	SDFile              *string
+	SDFileInterval      *time.Duration
+	BoshSpecPath        *string
+	MonitPath           *string
+	CollectorInterval   *time.Duration
+	TextfileDirectory   *string
+	CollectorConfigFile *string
+	RecordDir           *string
+	RecordMaxFrames     *int
+	ReplayDir           *string
+	MetricsNamespace    *string
+	MetricsSchemas      *[]string
+	MetricsEnvironment  *string
+	MetricsBoshName     *string
+	MetricsBoshUuid     *string
+	MetricsLabelDrop    *[]string
+	MetricsLabelRename  *map[string]string
+	MetricsLabelExtra   *map[string]string
+	MetricsProcessPid   *string
+	MetricsSelf         *bool
+	LogLevel            *string
+	LogPath             *string
+
+	EventsInterval             *time.Duration
+	EventsFilesystemThresholds *[]float64
+
+	NotifyWebhookURLs *[]string
+	NotifyTimeout     *time.Duration
+	NotifyMaxRetries  *int
+	NotifyMinBackoff  *time.Duration
+	NotifyMaxBackoff  *time.Duration
+	NotifyDedupWindow *time.Duration
+	NotifyRateLimit   *int
+	NotifyTLS         *TLSFlags
+
+	AlertsRulesFile        *string
+	AlertsAlertmanagerURLs *[]string
+	AlertsInterval         *time.Duration
+	AlertsTimeout          *time.Duration
+	AlertsTLS              *TLSFlags
+
+	PeersProbeEnabled *bool
+	PeersTargets      *[]string
+	PeersFile         *string
+	PeersScheme       *string
+	PeersMetricsPath  *string
+	PeersTimeout      *time.Duration
+	PeersTLS          *TLSFlags
+
+	RemoteWriteURL               *string
+	RemoteWriteInterval          *time.Duration
+	RemoteWriteTimeout           *time.Duration
+	RemoteWriteBasicAuthUsername *string
+	RemoteWriteBasicAuthPassword *string
+	RemoteWriteBearerToken       *string
+	RemoteWriteTLS               *TLSFlags
+	RemoteWriteMaxRetries        *int
+	RemoteWriteMinBackoff        *time.Duration
+	RemoteWriteMaxBackoff        *time.Duration
+	RemoteWriteBufferDir         *string
+	RemoteWriteBufferMaxBytes    *units.Base2Bytes
+
+	OTLPEndpoint *string
+	OTLPProtocol *string
+	OTLPInterval *time.Duration
+	OTLPTimeout  *time.Duration
+	OTLPHeaders  *map[string]string
+	OTLPTLS      *TLSFlags
+
+	PushGatewayURL        *string
+	PushJob               *string
+	PushInterval          *time.Duration
+	PushTimeout           *time.Duration
+	PushDeleteOnShutdown  *bool
+	PushBasicAuthUsername *string
+	PushBasicAuthPassword *string
+	PushTLS               *TLSFlags
+
+	StatsDAddress      *string
+	StatsDNetwork      *string
+	StatsDFlavor       *string
+	StatsDNameTemplate *string
+	StatsDInterval     *time.Duration
+
+	InfluxURL                 *string
+	InfluxOrg                 *string
+	InfluxBucket              *string
+	InfluxToken               *string
+	InfluxMeasurementTemplate *string
+	InfluxInterval            *time.Duration
+	InfluxTimeout             *time.Duration
+	InfluxTLS                 *TLSFlags
+
+	GraphiteAddress      *string
+	GraphitePathTemplate *string
+	GraphiteTags         *bool
+	GraphiteInterval     *time.Duration
+	GraphiteTimeout      *time.Duration
+
+	CheckFailingProcessesWarning  *int
+	CheckFailingProcessesCritical *int
+	CheckDiskWarning              *float64
+	CheckDiskCritical             *float64
+	CheckMemoryWarning            *float64
+	CheckMemoryCritical           *float64
+	CheckCertExpiryWarning        *time.Duration
+	CheckCertExpiryCritical       *time.Duration
+	CheckTimeout                  *time.Duration
+
+	DumpFormat *string
+
+	SimulateInstances  *int
+	SimulateScenario   *string
+	SimulatePeriod     *time.Duration
+	SimulateDeployment *string
 }
 
 func ParseConfig(programName, programHelp, programVersion string) *Config {
@@ -29,6 +181,22 @@ func ParseConfig(programName, programHelp, programVersion string) *Config {
 			"web.telemetry-path", "Path under which to expose Prometheus metrics ($BOSHI_EXPORTER_WEB_TELEMETRY_PATH)",
 		).Envar("BOSHI_EXPORTER_WEB_TELEMETRY_PATH").Default("/metrics").String(),
 
+		ReadyProcessInclude: app.Flag(
+			"web.ready-process-include", "Anchored regexp of the Monit process names checked by /readyz, all processes are checked when empty ($BOSHI_EXPORTER_WEB_READY_PROCESS_INCLUDE)",
+		).Envar("BOSHI_EXPORTER_WEB_READY_PROCESS_INCLUDE").Default("").String(),
+
+		ReadyProcessExclude: app.Flag(
+			"web.ready-process-exclude", "Anchored regexp of the Monit process names ignored by /readyz ($BOSHI_EXPORTER_WEB_READY_PROCESS_EXCLUDE)",
+		).Envar("BOSHI_EXPORTER_WEB_READY_PROCESS_EXCLUDE").Default("").String(),
+
+		SDFile: app.Flag(
+			"web.sd-file", "Prometheus file_sd JSON file describing the scrape target of the exporter (and of its peers), also served on /sd, not written when empty ($BOSHI_EXPORTER_WEB_SD_FILE)",
+		).Envar("BOSHI_EXPORTER_WEB_SD_FILE").Default("").String(),
+
+		SDFileInterval: app.Flag(
+			"web.sd-file-interval", "Interval of rewriting the file_sd file. Default: 1m ($BOSHI_EXPORTER_WEB_SD_FILE_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_WEB_SD_FILE_INTERVAL").Default("1m").Duration(),
+
 		BoshSpecPath: app.Flag(
 			"bosh.spec-path", "Path to the Bosh instance spec.json, default: /var/vcap/bosh/spec.json ($BOSHI_EXPORTER_BOSH_SPEC_PATH)",
 		).Envar("BOSHI_EXPORTER_BOSH_SPEC_PATH").Default("/var/vcap/bosh/spec.json").String(),
@@ -37,10 +205,36 @@ func ParseConfig(programName, programHelp, programVersion string) *Config {
 			"monit.path", "Path to the Monit program, default: /var/vcap/bosh/bin/monit ($BOSHI_EXPORTER_MONIT_PATH)",
 		).Envar("BOSHI_EXPORTER_MONIT_PATH").Default("/var/vcap/bosh/bin/monit").String(),
 
+		CollectorInterval: app.Flag(
+			"collector.interval", "Interval of fetching data in background goroutines, scrapes are served from the cached data. Default: 0s, fetch on every scrape ($BOSHI_EXPORTER_COLLECTOR_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_COLLECTOR_INTERVAL").Default("0s").Duration(),
+
+		TextfileDirectory: app.Flag(
+			"collector.textfile.directory", "Directory of the *.prom textfiles written by the Bosh jobs (e.g. /var/vcap/data/boshi_exporter/textfile), the textfile collector is disabled when empty ($BOSHI_EXPORTER_COLLECTOR_TEXTFILE_DIRECTORY)",
+		).Envar("BOSHI_EXPORTER_COLLECTOR_TEXTFILE_DIRECTORY").Default("").String(),
+
+		CollectorConfigFile: app.Flag(
+			"collector.config-file", "YAML file defining the exec collectors, the HTTP probes and the expected listeners of the Monit processes ($BOSHI_EXPORTER_COLLECTOR_CONFIG_FILE)",
+		).Envar("BOSHI_EXPORTER_COLLECTOR_CONFIG_FILE").Default("").String(),
+
+		RecordDir: app.Flag(
+			"record.dir", "Directory the raw monit output, spec.json and proc files of every scrape are recorded to as timestamped frames, recording is disabled when empty ($BOSHI_EXPORTER_RECORD_DIR)",
+		).Envar("BOSHI_EXPORTER_RECORD_DIR").Default("").String(),
+		RecordMaxFrames: app.Flag(
+			"record.max-frames", "Number of recorded frames kept per fetcher, the oldest frames are removed, unlimited when 0. Default: 1000 ($BOSHI_EXPORTER_RECORD_MAX_FRAMES)",
+		).Envar("BOSHI_EXPORTER_RECORD_MAX_FRAMES").Default("1000").Int(),
+		ReplayDir: app.Flag(
+			"replay.dir", "Directory of a recording replayed in place of the live sources, every scrape advances to the next frame in a loop ($BOSHI_EXPORTER_REPLAY_DIR)",
+		).Envar("BOSHI_EXPORTER_REPLAY_DIR").Default("").String(),
+
 		MetricsNamespace: app.Flag(
 			"metrics.namespace", "Metrics namespace, default: boshi ($BOSHI_EXPORTER_METRICS_NAMESPACE)",
 		).Envar("BOSHI_EXPORTER_METRICS_NAMESPACE").Default("boshi").String(),
 
+		MetricsSchemas: app.Flag(
+			"metrics.schema", "Metrics naming schema, can be: v1, v2 (process tree gauges without the _total suffix). Repeat the flag or give a comma-separated list to emit both schemas during a migration. Default: v1 ($BOSHI_EXPORTER_METRICS_SCHEMA)",
+		).Envar("BOSHI_EXPORTER_METRICS_SCHEMA").Default("v1").Strings(),
+
 		MetricsEnvironment: app.Flag(
 			"metrics.environment", "Environment label (e.g. prod/dev) to be attached to metrics ($BOSHI_EXPORTER_METRICS_ENVIRONMENT)",
 		).Envar("BOSHI_EXPORTER_METRICS_ENVIRONMENT").Default("").String(),
@@ -52,31 +246,650 @@ func ParseConfig(programName, programHelp, programVersion string) *Config {
 			"metrics.bosh-uuid", "Bosh director UUID label to be attached to metrics ($BOSHI_EXPORTER_METRICS_BOSH_UUID)",
 		).Envar("BOSHI_EXPORTER_METRICS_BOSH_UUID").Default("").String(),
 
+		MetricsLabelDrop: app.Flag(
+			"metrics.label-drop", "Label to be dropped from all metrics (e.g. bosh_instance_id), can be repeated ($BOSHI_EXPORTER_METRICS_LABEL_DROP)",
+		).Envar("BOSHI_EXPORTER_METRICS_LABEL_DROP").Strings(),
+		MetricsLabelRename: app.Flag(
+			"metrics.label-rename", "Label to be renamed in all metrics as old=new (e.g. bosh_instance_az=zone), can be repeated ($BOSHI_EXPORTER_METRICS_LABEL_RENAME)",
+		).Envar("BOSHI_EXPORTER_METRICS_LABEL_RENAME").StringMap(),
+		MetricsLabelExtra: app.Flag(
+			"metrics.label-extra", "Static label to be attached to all metrics as name=value (e.g. team=platform), can be repeated ($BOSHI_EXPORTER_METRICS_LABEL_EXTRA)",
+		).Envar("BOSHI_EXPORTER_METRICS_LABEL_EXTRA").StringMap(),
+		MetricsProcessPid: app.Flag(
+			"metrics.process-pid", "How Monit process PIDs are exposed, can be: label (process_pid labels), gauge (boshi_monit_process_pid gauges). Default: label ($BOSHI_EXPORTER_METRICS_PROCESS_PID)",
+		).Envar("BOSHI_EXPORTER_METRICS_PROCESS_PID").Default("label").Enum("label", "gauge"),
+		MetricsSelf: app.Flag(
+			"metrics.self", "Expose the exporter's own Go runtime, process and work metrics ($BOSHI_EXPORTER_METRICS_SELF)",
+		).Envar("BOSHI_EXPORTER_METRICS_SELF").Default("false").Bool(),
+
 		LogLevel: app.Flag(
 			"log.level", "Defines the minimum severity of messages that will be emitted, can be: debug, info, warn, error. Default: info. ($BOSHI_EXPORTER_LOG_LEVEL)",
 		).Envar("BOSHI_EXPORTER_LOG_LEVEL").Default("info").String(),
 		LogPath: app.Flag(
 			"log.path", "Specifies where logs are written, can be: stdout, stderr, any file path. Default: stdout ($BOSHI_EXPORTER_LOG_PATH)",
 		).Envar("BOSHI_EXPORTER_LOG_PATH").Default("stdout").String(),
+
+		EventsInterval: app.Flag(
+			"events.interval", "Interval of checking the instance state for changes streamed on /api/v1/events, events are disabled when 0. Default: 0s ($BOSHI_EXPORTER_EVENTS_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_EVENTS_INTERVAL").Default("0s").Duration(),
+		EventsFilesystemThresholds: app.Flag(
+			"events.filesystem-threshold", "Filesystem usage ratio whose crossing emits an event, can be repeated. Default: 0.8, 0.9 ($BOSHI_EXPORTER_EVENTS_FILESYSTEM_THRESHOLD)",
+		).Envar("BOSHI_EXPORTER_EVENTS_FILESYSTEM_THRESHOLD").Default("0.8", "0.9").Float64List(),
+
+		NotifyWebhookURLs: app.Flag(
+			"notify.webhook-url", "Webhook URL receiving CloudEvents when a process leaves the running status or Monit becomes unreachable, can be repeated, requires --events.interval ($BOSHI_EXPORTER_NOTIFY_WEBHOOK_URL)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_WEBHOOK_URL").Strings(),
+		NotifyTimeout: app.Flag(
+			"notify.timeout", "Timeout of a single webhook request. Default: 10s ($BOSHI_EXPORTER_NOTIFY_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_TIMEOUT").Default("10s").Duration(),
+		NotifyMaxRetries: app.Flag(
+			"notify.max-retries", "Maximum number of retries of a failed webhook request. Default: 3 ($BOSHI_EXPORTER_NOTIFY_MAX_RETRIES)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_MAX_RETRIES").Default("3").Int(),
+		NotifyMinBackoff: app.Flag(
+			"notify.min-backoff", "Initial backoff between webhook retries. Default: 1s ($BOSHI_EXPORTER_NOTIFY_MIN_BACKOFF)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_MIN_BACKOFF").Default("1s").Duration(),
+		NotifyMaxBackoff: app.Flag(
+			"notify.max-backoff", "Maximum backoff between webhook retries. Default: 10s ($BOSHI_EXPORTER_NOTIFY_MAX_BACKOFF)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_MAX_BACKOFF").Default("10s").Duration(),
+		NotifyDedupWindow: app.Flag(
+			"notify.dedup-window", "Window in which identical notifications are sent only once. Default: 5m ($BOSHI_EXPORTER_NOTIFY_DEDUP_WINDOW)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_DEDUP_WINDOW").Default("5m").Duration(),
+		NotifyRateLimit: app.Flag(
+			"notify.rate-limit", "Maximum number of notifications per minute, unlimited when 0. Default: 10 ($BOSHI_EXPORTER_NOTIFY_RATE_LIMIT)",
+		).Envar("BOSHI_EXPORTER_NOTIFY_RATE_LIMIT").Default("10").Int(),
+		NotifyTLS: newTLSFlags(app, "notify", "NOTIFY"),
+
+		AlertsRulesFile: app.Flag(
+			"alerts.rules-file", "YAML file of the alert rules evaluated on the collected metrics, alerting is disabled when empty ($BOSHI_EXPORTER_ALERTS_RULES_FILE)",
+		).Envar("BOSHI_EXPORTER_ALERTS_RULES_FILE").Default("").String(),
+		AlertsAlertmanagerURLs: app.Flag(
+			"alerts.alertmanager-url", "Alertmanager URL the alerts are posted to (e.g. http://localhost:9093), can be repeated ($BOSHI_EXPORTER_ALERTS_ALERTMANAGER_URL)",
+		).Envar("BOSHI_EXPORTER_ALERTS_ALERTMANAGER_URL").Strings(),
+		AlertsInterval: app.Flag(
+			"alerts.interval", "Interval of evaluating the alert rules. Default: 30s ($BOSHI_EXPORTER_ALERTS_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_ALERTS_INTERVAL").Default("30s").Duration(),
+		AlertsTimeout: app.Flag(
+			"alerts.timeout", "Timeout of a single Alertmanager request. Default: 10s ($BOSHI_EXPORTER_ALERTS_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_ALERTS_TIMEOUT").Default("10s").Duration(),
+		AlertsTLS: newTLSFlags(app, "alerts", "ALERTS"),
+
+		PeersProbeEnabled: app.Flag(
+			"peers.probe-enabled", "Serve /probe?target=<host:port> re-exposing the metrics of another boshi_exporter with the instance label set to the target ($BOSHI_EXPORTER_PEERS_PROBE_ENABLED)",
+		).Envar("BOSHI_EXPORTER_PEERS_PROBE_ENABLED").Default("false").Bool(),
+		PeersTargets: app.Flag(
+			"peers.target", "Peer boshi_exporter as host:port whose metrics are aggregated into the telemetry path with the instance label set, can be repeated ($BOSHI_EXPORTER_PEERS_TARGET)",
+		).Envar("BOSHI_EXPORTER_PEERS_TARGET").Strings(),
+		PeersFile: app.Flag(
+			"peers.file", "Prometheus file_sd JSON or YAML file listing the aggregated peers, re-read on every scrape ($BOSHI_EXPORTER_PEERS_FILE)",
+		).Envar("BOSHI_EXPORTER_PEERS_FILE").Default("").String(),
+		PeersScheme: app.Flag(
+			"peers.scheme", "Scheme of the peer scrapes, can be: http, https. Default: http ($BOSHI_EXPORTER_PEERS_SCHEME)",
+		).Envar("BOSHI_EXPORTER_PEERS_SCHEME").Default("http").Enum("http", "https"),
+		PeersMetricsPath: app.Flag(
+			"peers.metrics-path", "Telemetry path of the peers. Default: /metrics ($BOSHI_EXPORTER_PEERS_METRICS_PATH)",
+		).Envar("BOSHI_EXPORTER_PEERS_METRICS_PATH").Default("/metrics").String(),
+		PeersTimeout: app.Flag(
+			"peers.timeout", "Timeout of a single peer scrape. Default: 10s ($BOSHI_EXPORTER_PEERS_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_PEERS_TIMEOUT").Default("10s").Duration(),
+		PeersTLS: newTLSFlags(app, "peers", "PEERS"),
+
+		RemoteWriteURL: app.Flag(
+			"remote-write.url", "Prometheus remote_write URL the metrics are pushed to, push mode is disabled when empty ($BOSHI_EXPORTER_REMOTE_WRITE_URL)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_URL").Default("").String(),
+		RemoteWriteInterval: app.Flag(
+			"remote-write.interval", "Interval of pushing metrics to the remote_write URL. Default: 30s ($BOSHI_EXPORTER_REMOTE_WRITE_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_INTERVAL").Default("30s").Duration(),
+		RemoteWriteTimeout: app.Flag(
+			"remote-write.timeout", "Timeout of a single remote_write request. Default: 10s ($BOSHI_EXPORTER_REMOTE_WRITE_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_TIMEOUT").Default("10s").Duration(),
+		RemoteWriteBasicAuthUsername: app.Flag(
+			"remote-write.basic-auth-username", "Basic auth username for the remote_write URL ($BOSHI_EXPORTER_REMOTE_WRITE_BASIC_AUTH_USERNAME)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_BASIC_AUTH_USERNAME").Default("").String(),
+		RemoteWriteBasicAuthPassword: app.Flag(
+			"remote-write.basic-auth-password", "Basic auth password for the remote_write URL ($BOSHI_EXPORTER_REMOTE_WRITE_BASIC_AUTH_PASSWORD)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_BASIC_AUTH_PASSWORD").Default("").String(),
+		RemoteWriteBearerToken: app.Flag(
+			"remote-write.bearer-token", "Bearer token for the remote_write URL ($BOSHI_EXPORTER_REMOTE_WRITE_BEARER_TOKEN)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_BEARER_TOKEN").Default("").String(),
+		RemoteWriteTLS: newTLSFlags(app, "remote-write", "REMOTE_WRITE"),
+		RemoteWriteMaxRetries: app.Flag(
+			"remote-write.max-retries", "Number of retries of a failed remote_write request before it is buffered. Default: 3 ($BOSHI_EXPORTER_REMOTE_WRITE_MAX_RETRIES)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_MAX_RETRIES").Default("3").Int(),
+		RemoteWriteMinBackoff: app.Flag(
+			"remote-write.min-backoff", "Initial backoff between remote_write retries, doubled on every retry. Default: 1s ($BOSHI_EXPORTER_REMOTE_WRITE_MIN_BACKOFF)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_MIN_BACKOFF").Default("1s").Duration(),
+		RemoteWriteMaxBackoff: app.Flag(
+			"remote-write.max-backoff", "Maximum backoff between remote_write retries. Default: 10s ($BOSHI_EXPORTER_REMOTE_WRITE_MAX_BACKOFF)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_MAX_BACKOFF").Default("10s").Duration(),
+		RemoteWriteBufferDir: app.Flag(
+			"remote-write.buffer-dir", "Directory buffering requests which could not be sent during outages, disabled when empty ($BOSHI_EXPORTER_REMOTE_WRITE_BUFFER_DIR)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_BUFFER_DIR").Default("").String(),
+		RemoteWriteBufferMaxBytes: app.Flag(
+			"remote-write.buffer-max-bytes", "Maximum size of the buffer directory, the oldest requests are dropped first. Default: 64MB ($BOSHI_EXPORTER_REMOTE_WRITE_BUFFER_MAX_BYTES)",
+		).Envar("BOSHI_EXPORTER_REMOTE_WRITE_BUFFER_MAX_BYTES").Default("64MB").Bytes(),
+
+		OTLPEndpoint: app.Flag(
+			"otlp.endpoint", "OpenTelemetry collector endpoint the metrics are pushed to (e.g. http://localhost:4318), OTLP export is disabled when empty ($BOSHI_EXPORTER_OTLP_ENDPOINT)",
+		).Envar("BOSHI_EXPORTER_OTLP_ENDPOINT").Default("").String(),
+		OTLPProtocol: app.Flag(
+			"otlp.protocol", "OTLP protocol, can be: http/protobuf, grpc. Default: http/protobuf ($BOSHI_EXPORTER_OTLP_PROTOCOL)",
+		).Envar("BOSHI_EXPORTER_OTLP_PROTOCOL").Default("http/protobuf").Enum("http/protobuf", "grpc"),
+		OTLPInterval: app.Flag(
+			"otlp.interval", "Interval of pushing metrics to the OTLP endpoint. Default: 30s ($BOSHI_EXPORTER_OTLP_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_OTLP_INTERVAL").Default("30s").Duration(),
+		OTLPTimeout: app.Flag(
+			"otlp.timeout", "Timeout of a single OTLP export request. Default: 10s ($BOSHI_EXPORTER_OTLP_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_OTLP_TIMEOUT").Default("10s").Duration(),
+		OTLPHeaders: app.Flag(
+			"otlp.header", "Header sent with OTLP export requests as name=value (e.g. authorization), can be repeated ($BOSHI_EXPORTER_OTLP_HEADER)",
+		).Envar("BOSHI_EXPORTER_OTLP_HEADER").StringMap(),
+		OTLPTLS: newTLSFlags(app, "otlp", "OTLP"),
+
+		PushGatewayURL: app.Flag(
+			"push.gateway-url", "Pushgateway URL the metrics are pushed to, push mode is disabled when empty ($BOSHI_EXPORTER_PUSH_GATEWAY_URL)",
+		).Envar("BOSHI_EXPORTER_PUSH_GATEWAY_URL").Default("").String(),
+		PushJob: app.Flag(
+			"push.job", "Job name of the pushed metrics group. Default: boshi_exporter ($BOSHI_EXPORTER_PUSH_JOB)",
+		).Envar("BOSHI_EXPORTER_PUSH_JOB").Default("boshi_exporter").String(),
+		PushInterval: app.Flag(
+			"push.interval", "Interval of pushing metrics to the Pushgateway. Default: 30s ($BOSHI_EXPORTER_PUSH_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_PUSH_INTERVAL").Default("30s").Duration(),
+		PushTimeout: app.Flag(
+			"push.timeout", "Timeout of a single Pushgateway request. Default: 10s ($BOSHI_EXPORTER_PUSH_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_PUSH_TIMEOUT").Default("10s").Duration(),
+		PushDeleteOnShutdown: app.Flag(
+			"push.delete-on-shutdown", "Delete the metrics group from the Pushgateway on shutdown instead of the final push ($BOSHI_EXPORTER_PUSH_DELETE_ON_SHUTDOWN)",
+		).Envar("BOSHI_EXPORTER_PUSH_DELETE_ON_SHUTDOWN").Default("false").Bool(),
+		PushBasicAuthUsername: app.Flag(
+			"push.basic-auth-username", "Basic auth username for the Pushgateway ($BOSHI_EXPORTER_PUSH_BASIC_AUTH_USERNAME)",
+		).Envar("BOSHI_EXPORTER_PUSH_BASIC_AUTH_USERNAME").Default("").String(),
+		PushBasicAuthPassword: app.Flag(
+			"push.basic-auth-password", "Basic auth password for the Pushgateway ($BOSHI_EXPORTER_PUSH_BASIC_AUTH_PASSWORD)",
+		).Envar("BOSHI_EXPORTER_PUSH_BASIC_AUTH_PASSWORD").Default("").String(),
+		PushTLS: newTLSFlags(app, "push", "PUSH"),
+
+		StatsDAddress: app.Flag(
+			"statsd.address", "StatsD address the gauges are sent to, host:port or unix socket path, StatsD is disabled when empty ($BOSHI_EXPORTER_STATSD_ADDRESS)",
+		).Envar("BOSHI_EXPORTER_STATSD_ADDRESS").Default("").String(),
+		StatsDNetwork: app.Flag(
+			"statsd.network", "StatsD network, can be: udp, unixgram. Default: udp ($BOSHI_EXPORTER_STATSD_NETWORK)",
+		).Envar("BOSHI_EXPORTER_STATSD_NETWORK").Default("udp").Enum("udp", "unixgram"),
+		StatsDFlavor: app.Flag(
+			"statsd.flavor", "StatsD flavor, can be: dogstatsd (labels sent as tags), statsd. Default: dogstatsd ($BOSHI_EXPORTER_STATSD_FLAVOR)",
+		).Envar("BOSHI_EXPORTER_STATSD_FLAVOR").Default("dogstatsd").Enum("dogstatsd", "statsd"),
+		StatsDNameTemplate: app.Flag(
+			"statsd.name-template", "Go template of the StatsD metric name, the fields are .Name and .Labels (e.g. '{{ .Name }}.{{ .Labels.process_name }}'). Default: '{{ .Name }}' for dogstatsd, the name followed by the label values sorted by label name for statsd ($BOSHI_EXPORTER_STATSD_NAME_TEMPLATE)",
+		).Envar("BOSHI_EXPORTER_STATSD_NAME_TEMPLATE").Default("").String(),
+		StatsDInterval: app.Flag(
+			"statsd.interval", "Interval of sending gauges to StatsD. Default: 10s ($BOSHI_EXPORTER_STATSD_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_STATSD_INTERVAL").Default("10s").Duration(),
+
+		InfluxURL: app.Flag(
+			"influx.url", "InfluxDB URL the metrics are written to (e.g. http://localhost:8086), Influx output is disabled when empty ($BOSHI_EXPORTER_INFLUX_URL)",
+		).Envar("BOSHI_EXPORTER_INFLUX_URL").Default("").String(),
+		InfluxOrg: app.Flag(
+			"influx.org", "InfluxDB organization ($BOSHI_EXPORTER_INFLUX_ORG)",
+		).Envar("BOSHI_EXPORTER_INFLUX_ORG").Default("").String(),
+		InfluxBucket: app.Flag(
+			"influx.bucket", "InfluxDB bucket ($BOSHI_EXPORTER_INFLUX_BUCKET)",
+		).Envar("BOSHI_EXPORTER_INFLUX_BUCKET").Default("").String(),
+		InfluxToken: app.Flag(
+			"influx.token", "InfluxDB API token ($BOSHI_EXPORTER_INFLUX_TOKEN)",
+		).Envar("BOSHI_EXPORTER_INFLUX_TOKEN").Default("").String(),
+		InfluxMeasurementTemplate: app.Flag(
+			"influx.measurement-template", "Go template of the InfluxDB measurement, the fields are .Name and .Labels ($BOSHI_EXPORTER_INFLUX_MEASUREMENT_TEMPLATE)",
+		).Envar("BOSHI_EXPORTER_INFLUX_MEASUREMENT_TEMPLATE").Default("{{ .Name }}").String(),
+		InfluxInterval: app.Flag(
+			"influx.interval", "Interval of writing metrics to InfluxDB. Default: 30s ($BOSHI_EXPORTER_INFLUX_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_INFLUX_INTERVAL").Default("30s").Duration(),
+		InfluxTimeout: app.Flag(
+			"influx.timeout", "Timeout of a single InfluxDB write request. Default: 10s ($BOSHI_EXPORTER_INFLUX_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_INFLUX_TIMEOUT").Default("10s").Duration(),
+		InfluxTLS: newTLSFlags(app, "influx", "INFLUX"),
+
+		GraphiteAddress: app.Flag(
+			"graphite.address", "Graphite plaintext protocol address as host:port, Graphite output is disabled when empty ($BOSHI_EXPORTER_GRAPHITE_ADDRESS)",
+		).Envar("BOSHI_EXPORTER_GRAPHITE_ADDRESS").Default("").String(),
+		GraphitePathTemplate: app.Flag(
+			"graphite.path-template", "Go template of the Graphite metric path, the fields are .Name and .Labels, empty segments are removed ($BOSHI_EXPORTER_GRAPHITE_PATH_TEMPLATE)",
+		).Envar("BOSHI_EXPORTER_GRAPHITE_PATH_TEMPLATE").Default("{{ .Labels.bosh_deployment }}.{{ .Labels.bosh_instance_name }}.{{ .Labels.bosh_instance_index }}.{{ .Labels.process_name }}.{{ .Name }}").String(),
+		GraphiteTags: app.Flag(
+			"graphite.tags", "Append the metric labels as Graphite tags to the path ($BOSHI_EXPORTER_GRAPHITE_TAGS)",
+		).Envar("BOSHI_EXPORTER_GRAPHITE_TAGS").Default("false").Bool(),
+		GraphiteInterval: app.Flag(
+			"graphite.interval", "Interval of writing metrics to Graphite. Default: 30s ($BOSHI_EXPORTER_GRAPHITE_INTERVAL)",
+		).Envar("BOSHI_EXPORTER_GRAPHITE_INTERVAL").Default("30s").Duration(),
+		GraphiteTimeout: app.Flag(
+			"graphite.timeout", "Timeout of connecting and writing to Graphite. Default: 10s ($BOSHI_EXPORTER_GRAPHITE_TIMEOUT)",
+		).Envar("BOSHI_EXPORTER_GRAPHITE_TIMEOUT").Default("10s").Duration(),
 	}
+	app.Command(ServeCommand, "Serve the metrics and the API (default)").Default()
+	check := app.Command(CheckCommand, "Run all fetchers once and print a Nagios plugin status line with perfdata, "+
+		"the exit code is 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN)")
+	config.CheckFailingProcessesWarning = check.Flag(
+		"failing-processes-warning", "Number of Monit processes not running or not monitored raising WARNING, disabled when 0. Default: 0 ($BOSHI_EXPORTER_CHECK_FAILING_PROCESSES_WARNING)",
+	).Envar("BOSHI_EXPORTER_CHECK_FAILING_PROCESSES_WARNING").Default("0").Int()
+	config.CheckFailingProcessesCritical = check.Flag(
+		"failing-processes-critical", "Number of Monit processes not running or not monitored raising CRITICAL, disabled when 0. Default: 1 ($BOSHI_EXPORTER_CHECK_FAILING_PROCESSES_CRITICAL)",
+	).Envar("BOSHI_EXPORTER_CHECK_FAILING_PROCESSES_CRITICAL").Default("1").Int()
+	config.CheckDiskWarning = check.Flag(
+		"disk-warning", "Used ratio of the root, data or store disk raising WARNING. Default: 0.8 ($BOSHI_EXPORTER_CHECK_DISK_WARNING)",
+	).Envar("BOSHI_EXPORTER_CHECK_DISK_WARNING").Default("0.8").Float64()
+	config.CheckDiskCritical = check.Flag(
+		"disk-critical", "Used ratio of the root, data or store disk raising CRITICAL. Default: 0.9 ($BOSHI_EXPORTER_CHECK_DISK_CRITICAL)",
+	).Envar("BOSHI_EXPORTER_CHECK_DISK_CRITICAL").Default("0.9").Float64()
+	config.CheckMemoryWarning = check.Flag(
+		"memory-warning", "Used memory ratio raising WARNING. Default: 0.9 ($BOSHI_EXPORTER_CHECK_MEMORY_WARNING)",
+	).Envar("BOSHI_EXPORTER_CHECK_MEMORY_WARNING").Default("0.9").Float64()
+	config.CheckMemoryCritical = check.Flag(
+		"memory-critical", "Used memory ratio raising CRITICAL. Default: 0.95 ($BOSHI_EXPORTER_CHECK_MEMORY_CRITICAL)",
+	).Envar("BOSHI_EXPORTER_CHECK_MEMORY_CRITICAL").Default("0.95").Float64()
+	config.CheckCertExpiryWarning = check.Flag(
+		"cert-expiry-warning", "Remaining validity of a certificate of the probes in --collector.config-file raising WARNING. Default: 720h ($BOSHI_EXPORTER_CHECK_CERT_EXPIRY_WARNING)",
+	).Envar("BOSHI_EXPORTER_CHECK_CERT_EXPIRY_WARNING").Default("720h").Duration()
+	config.CheckCertExpiryCritical = check.Flag(
+		"cert-expiry-critical", "Remaining validity of a certificate of the probes in --collector.config-file raising CRITICAL. Default: 168h ($BOSHI_EXPORTER_CHECK_CERT_EXPIRY_CRITICAL)",
+	).Envar("BOSHI_EXPORTER_CHECK_CERT_EXPIRY_CRITICAL").Default("168h").Duration()
+	config.CheckTimeout = check.Flag(
+		"timeout", "Timeout of the check, UNKNOWN is reported when it is exceeded. Default: 30s ($BOSHI_EXPORTER_CHECK_TIMEOUT)",
+	).Envar("BOSHI_EXPORTER_CHECK_TIMEOUT").Default("30s").Duration()
+
+	dump := app.Command(DumpCommand, "Collect the metrics once and print them to stdout, the fetcher errors are printed to stderr "+
+		"and the exit code is 1 if any fetcher failed")
+	config.DumpFormat = dump.Flag(
+		"format", "Output format, can be: text, openmetrics, json. Default: text ($BOSHI_EXPORTER_DUMP_FORMAT)",
+	).Envar("BOSHI_EXPORTER_DUMP_FORMAT").Default(DumpFormatText).Enum(DumpFormatText, DumpFormatOpenMetrics, DumpFormatJSON)
+
+	simulate := app.Command(SimulateCommand, "Serve the metrics of synthetic Bosh instances for dashboard and alert rule development, "+
+		"the instances listen on consecutive ports starting at --web.listen-address")
+	config.SimulateInstances = simulate.Flag(
+		"instances", "Number of simulated instances. Default: 3 ($BOSHI_EXPORTER_SIMULATE_INSTANCES)",
+	).Envar("BOSHI_EXPORTER_SIMULATE_INSTANCES").Default("3").Int()
+	config.SimulateScenario = simulate.Flag(
+		"scenario", "Scenario played on the first instance, the other instances stay healthy, can be: crashloop, disk-fill, swap-storm. Default: crashloop ($BOSHI_EXPORTER_SIMULATE_SCENARIO)",
+	).Envar("BOSHI_EXPORTER_SIMULATE_SCENARIO").Default(ScenarioCrashloop).Enum(ScenarioCrashloop, ScenarioDiskFill, ScenarioSwapStorm)
+	config.SimulatePeriod = simulate.Flag(
+		"period", "Duration of a scenario cycle, the scenario starts over after each cycle. Default: 10m ($BOSHI_EXPORTER_SIMULATE_PERIOD)",
+	).Envar("BOSHI_EXPORTER_SIMULATE_PERIOD").Default("10m").Duration()
+	config.SimulateDeployment = simulate.Flag(
+		"deployment", "Bosh deployment name of the simulated instances. Default: simulation ($BOSHI_EXPORTER_SIMULATE_DEPLOYMENT)",
+	).Envar("BOSHI_EXPORTER_SIMULATE_DEPLOYMENT").Default("simulation").String()
+
 	app.Version(programVersion)
 	app.HelpFlag.Short('h')
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	config.Command = kingpin.MustParse(app.Parse(os.Args[1:]))
 	return config
 }
 
 type MetricsContext struct {
-	Namespace   string
-	Environment string
-	BoshName    string
-	BoshUuid    string
-}
-
-func (c *Config) CreateMetricsContext() *MetricsContext {
-	return &MetricsContext{
-		Namespace:   *c.MetricsNamespace,
-		Environment: *c.MetricsEnvironment,
-		BoshName:    *c.MetricsBoshName,
-		BoshUuid:    *c.MetricsBoshUuid,
+	Namespace         string
+	Schemas           []string
+	Environment       string
+	BoshName          string
+	BoshUuid          string
+	DropLabels        []string
+	RenameLabels      map[string]string
+	ExtraLabels       map[string]string
+	ProcessPidAsGauge bool
+}
+
+// CreateMetricsContext creates the metrics context and validates its label policy against the names of the labels
+// attached by the collectors, the renamed and extra labels must be valid and must not collide with another label
+func (c *Config) CreateMetricsContext(labelNames []string) (*MetricsContext, error) {
+	metricsContext := &MetricsContext{
+		Namespace:         *c.MetricsNamespace,
+		Schemas:           splitSchemas(*c.MetricsSchemas),
+		Environment:       *c.MetricsEnvironment,
+		BoshName:          *c.MetricsBoshName,
+		BoshUuid:          *c.MetricsBoshUuid,
+		DropLabels:        *c.MetricsLabelDrop,
+		RenameLabels:      *c.MetricsLabelRename,
+		ExtraLabels:       *c.MetricsLabelExtra,
+		ProcessPidAsGauge: *c.MetricsProcessPid == "gauge",
+	}
+	if err := metricsContext.validateSchemas(); err != nil {
+		return nil, err
+	}
+	if err := metricsContext.validateLabels(labelNames); err != nil {
+		return nil, err
+	}
+	return metricsContext, nil
+}
+
+// splitSchemas splits comma-separated schema values, no schema selects v1
+func splitSchemas(values []string) []string {
+	var schemas []string
+	for _, value := range values {
+		for _, schema := range strings.Split(value, ",") {
+			if schema = strings.TrimSpace(schema); schema != "" && !slices.Contains(schemas, schema) {
+				schemas = append(schemas, schema)
+			}
+		}
+	}
+	if len(schemas) == 0 {
+		return []string{SchemaV1}
+	}
+	return schemas
+}
+
+func (c *MetricsContext) validateSchemas() error {
+	for _, schema := range c.Schemas {
+		if !slices.Contains(metricsSchemas, schema) {
+			return fmt.Errorf("cannot use metrics schema '%s', known schemas: %v", schema, metricsSchemas)
+		}
+	}
+	return nil
+}
+
+func (c *MetricsContext) validateLabels(labelNames []string) error {
+	dropped := make(map[string]bool, len(c.DropLabels))
+	for _, name := range c.DropLabels {
+		dropped[name] = true
+	}
+	// exposed label names with the labels they originate from
+	exposed := make(map[string]string, len(labelNames))
+	for _, name := range labelNames {
+		if dropped[name] {
+			continue
+		}
+		exposedName := name
+		if renamed, ok := c.RenameLabels[name]; ok {
+			if !labelNameRegexp.MatchString(renamed) || strings.HasPrefix(renamed, "__") {
+				return fmt.Errorf("cannot rename label '%s', invalid label name '%s'", name, renamed)
+			}
+			exposedName = renamed
+		}
+		if other, ok := exposed[exposedName]; ok {
+			return fmt.Errorf("cannot rename label '%s', its name '%s' collides with label '%s'", name, exposedName, other)
+		}
+		exposed[exposedName] = name
+	}
+	for name := range c.ExtraLabels {
+		if !labelNameRegexp.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("cannot add extra label, invalid label name '%s'", name)
+		}
+		if other, ok := exposed[name]; ok {
+			return fmt.Errorf("cannot add extra label '%s', it collides with label '%s'", name, other)
+		}
+	}
+	return nil
+}
+
+// HasSchema reports whether metrics of the given naming schema should be emitted, no schema selects v1
+func (c *MetricsContext) HasSchema(schema string) bool {
+	if len(c.Schemas) == 0 {
+		return schema == SchemaV1
+	}
+	return slices.Contains(c.Schemas, schema)
+}
+
+type ReadinessContext struct {
+	ProcessInclude string
+	ProcessExclude string
+}
+
+func (c *Config) CreateReadinessContext() *ReadinessContext {
+	return &ReadinessContext{
+		ProcessInclude: *c.ReadyProcessInclude,
+		ProcessExclude: *c.ReadyProcessExclude,
+	}
+}
+
+type EventsContext struct {
+	Interval             time.Duration
+	FilesystemThresholds []float64
+}
+
+func (c *Config) CreateEventsContext() *EventsContext {
+	return &EventsContext{
+		Interval:             *c.EventsInterval,
+		FilesystemThresholds: *c.EventsFilesystemThresholds,
+	}
+}
+
+type NotifyContext struct {
+	WebhookURLs []string
+	Timeout     time.Duration
+	MaxRetries  int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	DedupWindow time.Duration
+	RateLimit   int
+	TLS         *TLSContext
+}
+
+func (c *Config) CreateNotifyContext() *NotifyContext {
+	return &NotifyContext{
+		WebhookURLs: *c.NotifyWebhookURLs,
+		Timeout:     *c.NotifyTimeout,
+		MaxRetries:  *c.NotifyMaxRetries,
+		MinBackoff:  *c.NotifyMinBackoff,
+		MaxBackoff:  *c.NotifyMaxBackoff,
+		DedupWindow: *c.NotifyDedupWindow,
+		RateLimit:   *c.NotifyRateLimit,
+		TLS:         c.NotifyTLS.createTLSContext(),
+	}
+}
+
+type AlertsContext struct {
+	RulesFile        string
+	AlertmanagerURLs []string
+	Interval         time.Duration
+	Timeout          time.Duration
+	TLS              *TLSContext
+}
+
+func (c *Config) CreateAlertsContext() *AlertsContext {
+	return &AlertsContext{
+		RulesFile:        *c.AlertsRulesFile,
+		AlertmanagerURLs: *c.AlertsAlertmanagerURLs,
+		Interval:         *c.AlertsInterval,
+		Timeout:          *c.AlertsTimeout,
+		TLS:              c.AlertsTLS.createTLSContext(),
+	}
+}
+
+type PeersContext struct {
+	ProbeEnabled bool
+	Targets      []string
+	File         string
+	Scheme       string
+	MetricsPath  string
+	Timeout      time.Duration
+	TLS          *TLSContext
+}
+
+func (c *Config) CreatePeersContext() *PeersContext {
+	return &PeersContext{
+		ProbeEnabled: *c.PeersProbeEnabled,
+		Targets:      *c.PeersTargets,
+		File:         *c.PeersFile,
+		Scheme:       *c.PeersScheme,
+		MetricsPath:  *c.PeersMetricsPath,
+		Timeout:      *c.PeersTimeout,
+		TLS:          c.PeersTLS.createTLSContext(),
+	}
+}
+
+// Aggregate reports whether the metrics of peers are aggregated into the telemetry path
+func (c *PeersContext) Aggregate() bool {
+	return len(c.Targets) > 0 || c.File != ""
+}
+
+type CheckContext struct {
+	FailingProcessesWarning  int
+	FailingProcessesCritical int
+	DiskWarning              float64
+	DiskCritical             float64
+	MemoryWarning            float64
+	MemoryCritical           float64
+	CertExpiryWarning        time.Duration
+	CertExpiryCritical       time.Duration
+	Timeout                  time.Duration
+}
+
+func (c *Config) CreateCheckContext() *CheckContext {
+	return &CheckContext{
+		FailingProcessesWarning:  *c.CheckFailingProcessesWarning,
+		FailingProcessesCritical: *c.CheckFailingProcessesCritical,
+		DiskWarning:              *c.CheckDiskWarning,
+		DiskCritical:             *c.CheckDiskCritical,
+		MemoryWarning:            *c.CheckMemoryWarning,
+		MemoryCritical:           *c.CheckMemoryCritical,
+		CertExpiryWarning:        *c.CheckCertExpiryWarning,
+		CertExpiryCritical:       *c.CheckCertExpiryCritical,
+		Timeout:                  *c.CheckTimeout,
+	}
+}
+
+type SimulateContext struct {
+	Instances  int
+	Scenario   string
+	Period     time.Duration
+	Deployment string
+}
+
+func (c *Config) CreateSimulateContext() *SimulateContext {
+	return &SimulateContext{
+		Instances:  *c.SimulateInstances,
+		Scenario:   *c.SimulateScenario,
+		Period:     *c.SimulatePeriod,
+		Deployment: *c.SimulateDeployment,
+	}
+}
+
+type RemoteWriteContext struct {
+	URL               string
+	Interval          time.Duration
+	Timeout           time.Duration
+	BasicAuthUsername string
+	BasicAuthPassword string
+	BearerToken       string
+	TLS               *TLSContext
+	MaxRetries        int
+	MinBackoff        time.Duration
+	MaxBackoff        time.Duration
+	BufferDir         string
+	BufferMaxBytes    int64
+}
+
+func (c *Config) CreateRemoteWriteContext() *RemoteWriteContext {
+	return &RemoteWriteContext{
+		URL:               *c.RemoteWriteURL,
+		Interval:          *c.RemoteWriteInterval,
+		Timeout:           *c.RemoteWriteTimeout,
+		BasicAuthUsername: *c.RemoteWriteBasicAuthUsername,
+		BasicAuthPassword: *c.RemoteWriteBasicAuthPassword,
+		BearerToken:       *c.RemoteWriteBearerToken,
+		TLS:               c.RemoteWriteTLS.createTLSContext(),
+		MaxRetries:        *c.RemoteWriteMaxRetries,
+		MinBackoff:        *c.RemoteWriteMinBackoff,
+		MaxBackoff:        *c.RemoteWriteMaxBackoff,
+		BufferDir:         *c.RemoteWriteBufferDir,
+		BufferMaxBytes:    int64(*c.RemoteWriteBufferMaxBytes),
+	}
+}
+
+type OTLPContext struct {
+	Endpoint string
+	Protocol string
+	Interval time.Duration
+	Timeout  time.Duration
+	Headers  map[string]string
+	TLS      *TLSContext
+}
+
+func (c *Config) CreateOTLPContext() *OTLPContext {
+	return &OTLPContext{
+		Endpoint: *c.OTLPEndpoint,
+		Protocol: *c.OTLPProtocol,
+		Interval: *c.OTLPInterval,
+		Timeout:  *c.OTLPTimeout,
+		Headers:  *c.OTLPHeaders,
+		TLS:      c.OTLPTLS.createTLSContext(),
+	}
+}
+
+type PushgatewayContext struct {
+	URL               string
+	Job               string
+	Interval          time.Duration
+	Timeout           time.Duration
+	DeleteOnShutdown  bool
+	BasicAuthUsername string
+	BasicAuthPassword string
+	TLS               *TLSContext
+}
+
+func (c *Config) CreatePushgatewayContext() *PushgatewayContext {
+	return &PushgatewayContext{
+		URL:               *c.PushGatewayURL,
+		Job:               *c.PushJob,
+		Interval:          *c.PushInterval,
+		Timeout:           *c.PushTimeout,
+		DeleteOnShutdown:  *c.PushDeleteOnShutdown,
+		BasicAuthUsername: *c.PushBasicAuthUsername,
+		BasicAuthPassword: *c.PushBasicAuthPassword,
+		TLS:               c.PushTLS.createTLSContext(),
+	}
+}
+
+type StatsDContext struct {
+	Address      string
+	Network      string
+	Flavor       string
+	NameTemplate string
+	Interval     time.Duration
+}
+
+func (c *Config) CreateStatsDContext() *StatsDContext {
+	return &StatsDContext{
+		Address:      *c.StatsDAddress,
+		Network:      *c.StatsDNetwork,
+		Flavor:       *c.StatsDFlavor,
+		NameTemplate: *c.StatsDNameTemplate,
+		Interval:     *c.StatsDInterval,
+	}
+}
+
+type InfluxContext struct {
+	URL                 string
+	Org                 string
+	Bucket              string
+	Token               string
+	MeasurementTemplate string
+	Interval            time.Duration
+	Timeout             time.Duration
+	TLS                 *TLSContext
+}
+
+func (c *Config) CreateInfluxContext() *InfluxContext {
+	return &InfluxContext{
+		URL:                 *c.InfluxURL,
+		Org:                 *c.InfluxOrg,
+		Bucket:              *c.InfluxBucket,
+		Token:               *c.InfluxToken,
+		MeasurementTemplate: *c.InfluxMeasurementTemplate,
+		Interval:            *c.InfluxInterval,
+		Timeout:             *c.InfluxTimeout,
+		TLS:                 c.InfluxTLS.createTLSContext(),
+	}
+}
+
+type GraphiteContext struct {
+	Address      string
+	PathTemplate string
+	Tags         bool
+	Interval     time.Duration
+	Timeout      time.Duration
+}
+
+func (c *Config) CreateGraphiteContext() *GraphiteContext {
+	return &GraphiteContext{
+		Address:      *c.GraphiteAddress,
+		PathTemplate: *c.GraphitePathTemplate,
+		Tags:         *c.GraphiteTags,
+		Interval:     *c.GraphiteInterval,
+		Timeout:      *c.GraphiteTimeout,
 	}
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsContext_ValidateLabels(t *testing.T) {
+	labelNames := []string{"bosh_deployment", "bosh_job", "process_name", "process_pid"}
+	tests := []struct {
+		name     string
+		context  MetricsContext
+		expected string
+	}{
+		{
+			name:    "valid",
+			context: MetricsContext{RenameLabels: map[string]string{"bosh_job": "job"}, ExtraLabels: map[string]string{"Env": "prod"}},
+		},
+		{
+			name:    "rename onto a dropped label",
+			context: MetricsContext{DropLabels: []string{"bosh_deployment"}, RenameLabels: map[string]string{"bosh_job": "bosh_deployment"}},
+		},
+		{
+			name:     "rename onto an existing label",
+			context:  MetricsContext{RenameLabels: map[string]string{"bosh_job": "bosh_deployment"}},
+			expected: "cannot rename label 'bosh_job', its name 'bosh_deployment' collides with label 'bosh_deployment'",
+		},
+		{
+			name:     "two renames onto the same name",
+			context:  MetricsContext{RenameLabels: map[string]string{"bosh_job": "job", "process_name": "job"}},
+			expected: "cannot rename label 'process_name', its name 'job' collides with label 'bosh_job'",
+		},
+		{
+			name:     "invalid rename",
+			context:  MetricsContext{RenameLabels: map[string]string{"bosh_job": "bosh-job"}},
+			expected: "cannot rename label 'bosh_job', invalid label name 'bosh-job'",
+		},
+		{
+			name:     "extra label matching a variable label",
+			context:  MetricsContext{ExtraLabels: map[string]string{"process_pid": "1"}},
+			expected: "cannot add extra label 'process_pid', it collides with label 'process_pid'",
+		},
+		{
+			name:     "extra label matching a renamed label",
+			context:  MetricsContext{RenameLabels: map[string]string{"bosh_job": "job"}, ExtraLabels: map[string]string{"job": "nats"}},
+			expected: "cannot add extra label 'job', it collides with label 'bosh_job'",
+		},
+		{
+			name:     "reserved extra label",
+			context:  MetricsContext{ExtraLabels: map[string]string{"__name__": "up"}},
+			expected: "cannot add extra label, invalid label name '__name__'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.context.validateLabels(labelNames)
+			if test.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestMetricsContext_Schemas(t *testing.T) {
+	assert.Equal(t, []string{"v1"}, splitSchemas(nil))
+	assert.Equal(t, []string{"v1"}, splitSchemas([]string{""}))
+	assert.Equal(t, []string{"v1", "v2"}, splitSchemas([]string{"v1, v2"}))
+	assert.Equal(t, []string{"v2", "v1"}, splitSchemas([]string{"v2", "v1,v2"}))
+
+	assert.True(t, (&MetricsContext{}).HasSchema("v1"), "no schema selects v1")
+	assert.False(t, (&MetricsContext{}).HasSchema("v2"))
+	assert.NoError(t, (&MetricsContext{Schemas: []string{"v1", "v2"}}).validateSchemas())
+	assert.EqualError(t, (&MetricsContext{Schemas: []string{"v3"}}).validateSchemas(), "cannot use metrics schema 'v3', known schemas: [v1 v2]")
+}
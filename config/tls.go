@@ -0,0 +1,87 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/alecthomas/kingpin/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+// TLSFlags holds the client TLS flags of a single outbound connection
+type TLSFlags struct {
+	CAFile             *string
+	CertFile           *string
+	KeyFile            *string
+	InsecureSkipVerify *bool
+}
+
+// newTLSFlags defines the TLS flags with the given flag name and environment variable prefixes
+func newTLSFlags(app *kingpin.Application, flagPrefix, envarPrefix string) *TLSFlags {
+	flag := func(name, envarSuffix, help string) *kingpin.FlagClause {
+		envar := "BOSHI_EXPORTER_" + envarPrefix + "_" + envarSuffix
+		return app.Flag(flagPrefix+"."+name, fmt.Sprintf("%s ($%s)", help, envar)).Envar(envar)
+	}
+	return &TLSFlags{
+		CAFile:             flag("tls-ca-file", "TLS_CA_FILE", "Path to the CA certificate used to verify the server").Default("").String(),
+		CertFile:           flag("tls-cert-file", "TLS_CERT_FILE", "Path to the client certificate").Default("").String(),
+		KeyFile:            flag("tls-key-file", "TLS_KEY_FILE", "Path to the client certificate key").Default("").String(),
+		InsecureSkipVerify: flag("tls-insecure-skip-verify", "TLS_INSECURE_SKIP_VERIFY", "Disable the server certificate verification").Default("false").Bool(),
+	}
+}
+
+func (f *TLSFlags) createTLSContext() *TLSContext {
+	return &TLSContext{
+		CAFile:             *f.CAFile,
+		CertFile:           *f.CertFile,
+		KeyFile:            *f.KeyFile,
+		InsecureSkipVerify: *f.InsecureSkipVerify,
+	}
+}
+
+// TLSContext holds the client TLS settings
+type TLSContext struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// NewHTTPClient creates an HTTP client with the given TLS settings and request timeout, tlsContext may be nil
+func NewHTTPClient(tlsContext *TLSContext, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsContext != nil {
+		tlsConfig, err := tlsContext.NewTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// NewTLSConfig builds the client TLS configuration
+func (c *TLSContext) NewTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile != "" {
+		caCert, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file '%s', error: %v", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("cannot parse CA file '%s'", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate '%s', error: %v", c.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"boshi_exporter/collectors"
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// runDump collects the metrics once and prints them to stdout, the returned exit code is 1 if a fetcher failed
+func runDump(cfg *config.Config) int {
+	metricsCtx, err := cfg.CreateMetricsContext(collectors.LabelNames)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid metrics configuration: %v\n", err)
+		return 1
+	}
+	allFetchers := fetchers.NewFetchers(*cfg.BoshSpecPath, *cfg.MonitPath)
+	snapshots, err := createSnapshots(cfg, allFetchers)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "cannot create fetchers: %v\n", err)
+		return 1
+	}
+	var extraCollectors []prometheus.Collector
+	if *cfg.MetricsSelf {
+		extraCollectors = createSelfCollectors(metricsCtx, allFetchers)
+	}
+	registry, collector, err := createRegistry(metricsCtx, snapshots, extraCollectors...)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "spec fetcher failed: %v\n", err)
+		return 1
+	}
+	groupCollectors, err := createGroupCollectors(cfg, metricsCtx, snapshots, collector, extraCollectors)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "cannot create collectors: %v\n", err)
+		return 1
+	}
+	for _, c := range groupCollectors {
+		registry.MustRegister(c)
+	}
+	return dumpMetrics(os.Stdout, os.Stderr, registry, snapshots, *cfg.DumpFormat)
+}
+
+// dumpMetrics gathers the metrics once and writes them to stdout and the errors to stderr,
+// the returned exit code is 1 if a fetcher or the gathering failed
+func dumpMetrics(stdout, stderr io.Writer, gatherer prometheus.Gatherer, snapshots *fetchers.Snapshots, format string) int {
+	families, gatherErr := gatherer.Gather()
+	failed := reportFetchError(stderr, snapshots.Spec.Name(), snapshots.Spec.Last().Err)
+	failed = reportFetchError(stderr, snapshots.Monit.Name(), snapshots.Monit.Last().Err) || failed
+	failed = reportFetchError(stderr, snapshots.System.Name(), snapshots.System.Last().Err) || failed
+	if gatherErr != nil {
+		_, _ = fmt.Fprintf(stderr, "gathering metrics failed: %v\n", gatherErr)
+		failed = true
+	}
+	out := bufio.NewWriter(stdout)
+	if err := writeFamilies(out, families, format); err != nil {
+		_, _ = fmt.Fprintf(stderr, "cannot write metrics: %v\n", err)
+		return 1
+	}
+	if err := out.Flush(); err != nil {
+		return 1
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// reportFetchError prints the fetcher error and reports whether the fetcher failed
+func reportFetchError(stderr io.Writer, name string, err error) bool {
+	if err == nil {
+		return false
+	}
+	_, _ = fmt.Fprintf(stderr, "%s fetcher failed: %v\n", name, err)
+	return true
+}
+
+// writeFamilies encodes the metric families in the text, openmetrics or json format,
+// the json format is an array of the metric families in the protobuf JSON mapping
+func writeFamilies(w io.Writer, families []*dto.MetricFamily, format string) error {
+	switch format {
+	case config.DumpFormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		for i, family := range families {
+			data, err := protojson.Marshal(family)
+			if err != nil {
+				return err
+			}
+			separator := ",\n"
+			if i == 0 {
+				separator = "\n"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s", separator, data); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "\n]\n")
+		return err
+	case config.DumpFormatOpenMetrics:
+		return encodeFamilies(w, families, expfmt.NewFormat(expfmt.TypeOpenMetrics))
+	default:
+		return encodeFamilies(w, families, expfmt.NewFormat(expfmt.TypeTextPlain))
+	}
+}
+
+func encodeFamilies(w io.Writer, families []*dto.MetricFamily, format expfmt.Format) error {
+	encoder := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDumpSnapshots returns empty snapshots, the dump test only checks the reported fetch errors and the exit code
+func newDumpSnapshots(monitErr error) *fetchers.Snapshots {
+	return &fetchers.Snapshots{
+		Spec: fetchers.NewSnapshotSource("spec", func(context.Context) (*fetchers.InstanceSpec, error) {
+			return &fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats"}, nil
+		}),
+		Monit: fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+			return &fetchers.MonitStat{}, monitErr
+		}),
+		System: fetchers.NewSnapshotSource("system", func(context.Context) (*fetchers.SystemStat, error) {
+			return &fetchers.SystemStat{Host: &fetchers.HostStat{}, CPU: &fetchers.CPUStat{}, Memory: &fetchers.MemoryStat{}, Disks: &fetchers.DisksStat{}}, nil
+		}),
+	}
+}
+
+func gatherDumpFamilies(t *testing.T) []*dto.MetricFamily {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "boshi_monit_process_uptime_seconds", Help: "Monit process uptime since last start (seconds)"}, []string{"process_name"})
+	gauge.WithLabelValues("nats").Set(60)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(gauge))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	return families
+}
+
+func TestWriteFamilies(t *testing.T) {
+	families := gatherDumpFamilies(t)
+
+	var text bytes.Buffer
+	require.NoError(t, writeFamilies(&text, families, config.DumpFormatText))
+	assert.Equal(t, `# HELP boshi_monit_process_uptime_seconds Monit process uptime since last start (seconds)
+# TYPE boshi_monit_process_uptime_seconds gauge
+boshi_monit_process_uptime_seconds{process_name="nats"} 60
+`, text.String())
+
+	var openMetrics bytes.Buffer
+	require.NoError(t, writeFamilies(&openMetrics, families, config.DumpFormatOpenMetrics))
+	assert.Equal(t, `# HELP boshi_monit_process_uptime_seconds Monit process uptime since last start (seconds)
+# TYPE boshi_monit_process_uptime_seconds gauge
+boshi_monit_process_uptime_seconds{process_name="nats"} 60.0
+# EOF
+`, openMetrics.String())
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, writeFamilies(&jsonOut, families, config.DumpFormatJSON))
+	var decoded []map[string]any
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded), jsonOut.String())
+	require.Len(t, decoded, 1)
+	assert.Equal(t, "boshi_monit_process_uptime_seconds", decoded[0]["name"])
+	assert.Equal(t, "GAUGE", decoded[0]["type"])
+
+	var empty bytes.Buffer
+	require.NoError(t, writeFamilies(&empty, nil, config.DumpFormatJSON))
+	assert.JSONEq(t, `[]`, empty.String())
+}
+
+func TestDumpMetrics(t *testing.T) {
+	metricsContext := &config.MetricsContext{Namespace: "boshi"}
+	for _, test := range []struct {
+		name     string
+		monitErr error
+		code     int
+		stderr   string
+	}{
+		{name: "success", code: 0},
+		{name: "failed fetcher", monitErr: errors.New("connection refused"), code: 1, stderr: "monit fetcher failed: connection refused\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			snapshots := newDumpSnapshots(test.monitErr)
+			registry, _, err := createRegistry(metricsContext, snapshots)
+			require.NoError(t, err)
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, test.code, dumpMetrics(&stdout, &stderr, registry, snapshots, config.DumpFormatText))
+			assert.Equal(t, test.stderr, stderr.String())
+			assert.Contains(t, stdout.String(), `boshi_instance_info`, "the metrics are written even if a fetcher failed")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// subscriberBuffer is the number of events buffered per subscriber, events are dropped for slow subscribers
+const subscriberBuffer = 64
+
+// Broker fans out published events to all subscribers
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving all events published from now on and a function ending the subscription,
+// the channel is closed when the subscription ends or the broker is closed
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish assigns the next ID to the event and sends it to all subscribers without blocking
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.lastID++
+	event.ID = b.lastID
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			zap.L().Warn("Dropping event for slow subscriber", zap.String("type", event.Type), zap.Uint64("id", event.ID))
+		}
+	}
+}
+
+// Close ends all subscriptions
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,27 @@
+package events
+
+import "time"
+
+// Types of the instance state change events
+const (
+	ProcessStatusChanged           = "process_status_changed"
+	ProcessMonitoringStatusChanged = "process_monitoring_status_changed"
+	ProcessPidChanged              = "process_pid_changed"
+	SpecChanged                    = "spec_changed"
+	FilesystemThresholdCrossed     = "filesystem_threshold_crossed"
+	MonitUnreachable               = "monit_unreachable"
+	MonitReachable                 = "monit_reachable"
+)
+
+// Event describes a change of the Bosh instance state, Old and New hold the values before and after the change
+type Event struct {
+	ID         uint64            `json:"id"`
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	Labels     map[string]string `json:"labels"`
+	Process    string            `json:"process,omitempty"`
+	Filesystem string            `json:"filesystem,omitempty"`
+	Threshold  float64           `json:"threshold,omitempty"`
+	Old        any               `json:"old"`
+	New        any               `json:"new"`
+}
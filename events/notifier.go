@@ -0,0 +1,263 @@
+package events
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runningStatus is the Monit status of a healthy process
+const runningStatus = "running"
+
+// webhookQueueSize is the number of notifications queued per webhook, notifications are dropped for slow webhooks
+const webhookQueueSize = 64
+
+// CloudEvents types of the notifications
+const (
+	ProcessNotRunningCloudEvent = "io.boshi.process.not_running"
+	MonitUnreachableCloudEvent  = "io.boshi.monit.unreachable"
+)
+
+// CloudEvent is a CloudEvents 1.0 event in the structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string           `json:"specversion"`
+	ID              string           `json:"id"`
+	Source          string           `json:"source"`
+	Type            string           `json:"type"`
+	Subject         string           `json:"subject,omitempty"`
+	Time            time.Time        `json:"time"`
+	DataContentType string           `json:"datacontenttype"`
+	Data            NotificationData `json:"data"`
+}
+
+// NotificationData is the data of the notification CloudEvents
+type NotificationData struct {
+	Deployment    string `json:"deployment"`
+	Instance      string `json:"instance"`
+	InstanceIndex int    `json:"instance_index"`
+	InstanceID    string `json:"instance_id"`
+	AZ            string `json:"az"`
+	Process       string `json:"process,omitempty"`
+	OldStatus     string `json:"old_status,omitempty"`
+	NewStatus     string `json:"new_status,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Notifier posts CloudEvents to webhooks when a process leaves the running status or Monit becomes unreachable.
+// Identical notifications are sent once per de-duplication window and the number of notifications per minute is limited.
+// Every webhook is delivered to by its own worker, so a slow webhook neither delays the others nor the subscription.
+type Notifier struct {
+	notifyContext *config.NotifyContext
+	spec          *fetchers.InstanceSpec
+	client        *http.Client
+	webhooks      []*webhook
+
+	sent   map[string]time.Time // last send time by de-duplication key
+	recent []time.Time          // send times within the last minute
+}
+
+// webhook is the delivery queue of a webhook URL
+type webhook struct {
+	url   string
+	queue chan notification
+}
+
+// notification is an encoded CloudEvent waiting for delivery
+type notification struct {
+	eventType string
+	body      []byte
+}
+
+func NewNotifier(notifyContext *config.NotifyContext, spec *fetchers.InstanceSpec) (*Notifier, error) {
+	client, err := config.NewHTTPClient(notifyContext.TLS, notifyContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]*webhook, 0, len(notifyContext.WebhookURLs))
+	for _, url := range notifyContext.WebhookURLs {
+		webhooks = append(webhooks, &webhook{url: url, queue: make(chan notification, webhookQueueSize)})
+	}
+	return &Notifier{
+		notifyContext: notifyContext,
+		spec:          spec,
+		client:        client,
+		webhooks:      webhooks,
+		sent:          make(map[string]time.Time),
+	}, nil
+}
+
+// Run sends notifications for the events of a broker subscription until the subscription ends or ctx is done,
+// the queued notifications are delivered before Run returns
+func (n *Notifier) Run(ctx context.Context, events <-chan Event) {
+	var wg sync.WaitGroup
+	for _, w := range n.webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.runWebhook(ctx, w)
+		}()
+	}
+	defer wg.Wait()
+	defer n.closeQueues()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			n.notify(event)
+		}
+	}
+}
+
+// runWebhook delivers the queued notifications of a webhook until its queue is closed or ctx is done
+func (n *Notifier) runWebhook(ctx context.Context, w *webhook) {
+	for notification := range w.queue {
+		if ctx.Err() != nil {
+			return
+		}
+		n.deliver(ctx, w.url, notification)
+	}
+}
+
+func (n *Notifier) closeQueues() {
+	for _, w := range n.webhooks {
+		close(w.queue)
+	}
+}
+
+// notify queues the notification of an event for all webhooks without blocking
+func (n *Notifier) notify(event Event) {
+	cloudEvent, key, ok := n.cloudEvent(event)
+	if !ok {
+		return
+	}
+	if last, ok := n.sent[key]; ok && event.Time.Sub(last) < n.notifyContext.DedupWindow {
+		zap.L().Debug("Skipping duplicate notification", zap.String("key", key))
+		return
+	}
+	if !n.allow(event.Time) {
+		zap.L().Warn("Dropping notification, rate limit exceeded", zap.String("key", key), zap.Int("rate_limit", n.notifyContext.RateLimit))
+		return
+	}
+	n.sent[key] = event.Time
+	body, err := json.Marshal(cloudEvent)
+	if err != nil {
+		zap.L().Error("Failed to encode notification", zap.Error(err))
+		return
+	}
+	for _, w := range n.webhooks {
+		select {
+		case w.queue <- notification{eventType: cloudEvent.Type, body: body}:
+		default:
+			zap.L().Warn("Dropping notification for slow webhook", zap.String("url", w.url), zap.String("type", cloudEvent.Type))
+		}
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, url string, notification notification) {
+	if err := n.sendWithRetries(ctx, url, notification.body); err != nil {
+		zap.L().Error("Failed to send notification", zap.String("url", url), zap.String("type", notification.eventType), zap.Error(err))
+	}
+}
+
+// cloudEvent converts the events which need a notification, the key identifies duplicate notifications
+func (n *Notifier) cloudEvent(event Event) (*CloudEvent, string, bool) {
+	data := NotificationData{
+		Deployment:    n.spec.Deployment,
+		Instance:      n.spec.Name,
+		InstanceIndex: n.spec.Index,
+		InstanceID:    n.spec.ID,
+		AZ:            n.spec.AZ,
+	}
+	var eventType string
+	switch event.Type {
+	case ProcessStatusChanged:
+		oldStatus, _ := event.Old.(string)
+		newStatus, _ := event.New.(string)
+		if oldStatus != runningStatus || newStatus == runningStatus {
+			return nil, "", false
+		}
+		eventType = ProcessNotRunningCloudEvent
+		data.Process, data.OldStatus, data.NewStatus = event.Process, oldStatus, newStatus
+	case MonitUnreachable:
+		eventType = MonitUnreachableCloudEvent
+		data.Error, _ = event.New.(string)
+	default:
+		return nil, "", false
+	}
+	return &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              n.spec.ID + "-" + strconv.FormatUint(event.ID, 10) + "-" + strconv.FormatInt(event.Time.UnixNano(), 10),
+		Source:          "/bosh/" + n.spec.Deployment + "/" + n.spec.Name + "/" + n.spec.ID,
+		Type:            eventType,
+		Subject:         data.Process,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            data,
+	}, eventType + "/" + data.Process + "/" + data.NewStatus, true
+}
+
+// allow reports whether another notification fits into the rate limit of the last minute
+func (n *Notifier) allow(now time.Time) bool {
+	recent := n.recent[:0]
+	for _, sent := range n.recent {
+		if now.Sub(sent) < time.Minute {
+			recent = append(recent, sent)
+		}
+	}
+	n.recent = recent
+	if n.notifyContext.RateLimit > 0 && len(n.recent) >= n.notifyContext.RateLimit {
+		return false
+	}
+	n.recent = append(n.recent, now)
+	return true
+}
+
+func (n *Notifier) sendWithRetries(ctx context.Context, url string, body []byte) error {
+	backoff := n.notifyContext.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := n.send(ctx, url, body)
+		if err == nil || attempt >= n.notifyContext.MaxRetries {
+			return err
+		}
+		zap.L().Debug("Retrying notification", zap.String("url", url), zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, n.notifyContext.MaxBackoff)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("webhook returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(message))
+}
@@ -0,0 +1,173 @@
+package events
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookStub struct {
+	mu       sync.Mutex
+	failures int // number of requests failing before the first success
+	received []CloudEvent
+	requests int
+}
+
+func (s *webhookStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.requests <= s.failures {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var event CloudEvent
+	if r.Header.Get("Content-Type") != "application/cloudevents+json; charset=utf-8" || json.NewDecoder(r.Body).Decode(&event) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.received = append(s.received, event)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newTestNotifier(t *testing.T, stub *webhookStub, rateLimit int) *Notifier {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	notifier, err := NewNotifier(&config.NotifyContext{
+		WebhookURLs: []string{server.URL},
+		Timeout:     time.Second,
+		MaxRetries:  2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		DedupWindow: time.Minute,
+		RateLimit:   rateLimit,
+	}, &fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats", Index: 1, ID: "b36ca4c9", AZ: "z2"})
+	require.NoError(t, err)
+	return notifier
+}
+
+// notify queues the notification of the event and delivers the queued notifications
+func notify(notifier *Notifier, event Event) {
+	notifier.notify(event)
+	for _, w := range notifier.webhooks {
+		for len(w.queue) > 0 {
+			notifier.deliver(context.Background(), w.url, <-w.queue)
+		}
+	}
+}
+
+func stopped(process string, at time.Time) Event {
+	return Event{ID: 1, Type: ProcessStatusChanged, Time: at, Process: process, Old: "running", New: "Does not exist"}
+}
+
+func TestNotifier_ProcessNotRunning(t *testing.T) {
+	stub := &webhookStub{failures: 2}
+	notifier := newTestNotifier(t, stub, 0)
+	now := time.Unix(1700000000, 0).UTC()
+
+	notify(notifier, stopped("nats", now))
+	require.Len(t, stub.received, 1, "the notification is retried")
+	event := stub.received[0]
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.Equal(t, ProcessNotRunningCloudEvent, event.Type)
+	assert.Equal(t, "/bosh/test-dev/nats/b36ca4c9", event.Source)
+	assert.Equal(t, "nats", event.Subject)
+	assert.Equal(t, now, event.Time)
+	assert.Equal(t, NotificationData{
+		Deployment: "test-dev", Instance: "nats", InstanceIndex: 1, InstanceID: "b36ca4c9", AZ: "z2",
+		Process: "nats", OldStatus: "running", NewStatus: "Does not exist",
+	}, event.Data)
+}
+
+func TestNotifier_Filter(t *testing.T) {
+	stub := &webhookStub{}
+	notifier := newTestNotifier(t, stub, 0)
+	now := time.Now()
+
+	notify(notifier, Event{Type: ProcessStatusChanged, Time: now, Process: "nats", Old: "Does not exist", New: "running"})
+	notify(notifier, Event{Type: ProcessPidChanged, Time: now, Process: "nats", Old: "42", New: "43"})
+	assert.Empty(t, stub.received)
+
+	notify(notifier, Event{Type: MonitUnreachable, Time: now, New: "monit is down"})
+	require.Len(t, stub.received, 1)
+	assert.Equal(t, MonitUnreachableCloudEvent, stub.received[0].Type)
+	assert.Equal(t, "monit is down", stub.received[0].Data.Error)
+}
+
+func TestNotifier_DedupAndRateLimit(t *testing.T) {
+	stub := &webhookStub{}
+	notifier := newTestNotifier(t, stub, 2)
+	now := time.Now()
+
+	notify(notifier, stopped("nats", now))
+	notify(notifier, stopped("nats", now.Add(time.Second)))
+	assert.Len(t, stub.received, 1, "duplicates within the window are skipped")
+	notify(notifier, stopped("nats", now.Add(2*time.Minute)))
+	assert.Len(t, stub.received, 2, "duplicates after the window are sent")
+
+	notify(notifier, stopped("bosh-dns", now.Add(2*time.Minute)))
+	notify(notifier, stopped("syslog", now.Add(2*time.Minute)))
+	assert.Len(t, stub.received, 3, "notifications above the rate limit are dropped")
+}
+
+func TestNotifier_Run(t *testing.T) {
+	stub := &webhookStub{}
+	notifier := newTestNotifier(t, stub, 0)
+	broker := NewBroker()
+	events, _ := broker.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		notifier.Run(context.Background(), events)
+		close(done)
+	}()
+	broker.Publish(stopped("nats", time.Now()))
+	broker.Close()
+	<-done
+	assert.Len(t, stub.received, 1)
+}
+
+func TestNotifier_RunSlowWebhook(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(slow.Close)
+	stub := &webhookStub{}
+	fast := httptest.NewServer(stub)
+	t.Cleanup(fast.Close)
+	notifier, err := NewNotifier(&config.NotifyContext{
+		WebhookURLs: []string{slow.URL, fast.URL},
+		Timeout:     time.Minute,
+		DedupWindow: time.Minute,
+	}, &fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats", ID: "b36ca4c9"})
+	require.NoError(t, err)
+	broker := NewBroker()
+	events, _ := broker.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		notifier.Run(context.Background(), events)
+		close(done)
+	}()
+
+	now := time.Now()
+	broker.Publish(stopped("nats", now))
+	broker.Publish(stopped("syslog", now))
+	assert.Eventually(t, func() bool {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		return len(stub.received) == 2
+	}, 5*time.Second, 10*time.Millisecond, "the slow webhook does not delay the others")
+	close(release)
+	broker.Close()
+	<-done
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEHandler(t *testing.T) {
+	broker := NewBroker()
+	server := httptest.NewServer(NewSSEHandler(broker))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the subscription is registered before the response headers are sent
+	broker.Publish(Event{Type: ProcessStatusChanged, Time: time.Unix(0, 0).UTC(), Process: "nats", Old: "running", New: "Does not exist"})
+	broker.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{
+		"id: 1",
+		"event: process_status_changed",
+		`data: {"id":1,"type":"process_status_changed","time":"1970-01-01T00:00:00Z","labels":null,"process":"nats","old":"running","new":"Does not exist"}`,
+		"",
+	}, lines)
+}
+
+func TestBroker_Unsubscribe(t *testing.T) {
+	broker := NewBroker()
+	events, unsubscribe := broker.Subscribe()
+	unsubscribe()
+	broker.Publish(Event{Type: SpecChanged})
+	_, ok := <-events
+	assert.False(t, ok)
+	unsubscribe()
+	broker.Close()
+}
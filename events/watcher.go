@@ -0,0 +1,142 @@
+package events
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// Watcher polls the snapshots and publishes an event for every detected change of the instance state
+type Watcher struct {
+	eventsContext *config.EventsContext
+	snapshots     *fetchers.Snapshots
+	broker        *Broker
+	labels        map[string]string
+
+	spec        *fetchers.InstanceSpec
+	monitErr    error
+	processes   map[string]fetchers.MonitProcessStatus
+	filesystems map[string]float64 // usage ratio by filesystem path
+}
+
+func NewWatcher(eventsContext *config.EventsContext, snapshots *fetchers.Snapshots, broker *Broker, labels map[string]string) *Watcher {
+	return &Watcher{eventsContext: eventsContext, snapshots: snapshots, broker: broker, labels: labels}
+}
+
+// Run polls the snapshots every interval until ctx is done, then it closes the broker
+func (w *Watcher) Run(ctx context.Context) {
+	defer w.broker.Close()
+	ticker := time.NewTicker(w.eventsContext.Interval)
+	defer ticker.Stop()
+	for {
+		w.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll compares the latest snapshots with the previous ones, the first successful poll of a source only
+// records the state, failed fetches keep the previous state
+func (w *Watcher) poll(ctx context.Context) {
+	now := time.Now()
+	if snapshot := w.snapshots.Spec.Get(ctx); snapshot.Value != nil {
+		w.diffSpec(now, snapshot.Value)
+	}
+	monitSnapshot := w.snapshots.Monit.Get(ctx)
+	w.diffMonitErr(now, monitSnapshot.Err)
+	if monitSnapshot.Err == nil && monitSnapshot.Value != nil {
+		w.diffProcesses(now, monitSnapshot.Value.Processes)
+	}
+	if snapshot := w.snapshots.System.Get(ctx); snapshot.Value != nil && snapshot.Value.Disks != nil {
+		w.diffFilesystems(now, snapshot.Value.Disks)
+	}
+}
+
+func (w *Watcher) diffSpec(now time.Time, spec *fetchers.InstanceSpec) {
+	if w.spec != nil && !reflect.DeepEqual(w.spec, spec) {
+		w.publish(Event{Type: SpecChanged, Time: now, Old: w.spec, New: spec})
+	}
+	w.spec = spec
+}
+
+// diffMonitErr publishes when Monit stops or starts responding, Monit is assumed reachable before the first poll
+func (w *Watcher) diffMonitErr(now time.Time, err error) {
+	switch {
+	case err != nil && w.monitErr == nil:
+		w.publish(Event{Type: MonitUnreachable, Time: now, New: err.Error()})
+	case err == nil && w.monitErr != nil:
+		w.publish(Event{Type: MonitReachable, Time: now, Old: w.monitErr.Error()})
+	}
+	w.monitErr = err
+}
+
+func (w *Watcher) diffProcesses(now time.Time, processes map[string]fetchers.MonitProcessStatus) {
+	if w.processes != nil {
+		names := make(map[string]struct{}, len(processes)+len(w.processes))
+		for name := range processes {
+			names[name] = struct{}{}
+		}
+		for name := range w.processes {
+			names[name] = struct{}{}
+		}
+		for _, name := range sortedNames(names) {
+			// processes added to or removed from Monit change from or to an empty state
+			old, current := w.processes[name], processes[name]
+			if old.Status != current.Status {
+				w.publish(Event{Type: ProcessStatusChanged, Time: now, Process: name, Old: old.Status, New: current.Status})
+			}
+			if old.MonitoringStatus != current.MonitoringStatus {
+				w.publish(Event{Type: ProcessMonitoringStatusChanged, Time: now, Process: name, Old: old.MonitoringStatus, New: current.MonitoringStatus})
+			}
+			if old.PID != current.PID {
+				w.publish(Event{Type: ProcessPidChanged, Time: now, Process: name, Old: old.PID, New: current.PID})
+			}
+		}
+	}
+	w.processes = processes
+}
+
+func (w *Watcher) diffFilesystems(now time.Time, disks *fetchers.DisksStat) {
+	filesystems := make(map[string]float64)
+	for _, usage := range []*disk.UsageStat{disks.RootDisk, disks.DataDisk, disks.StoreDisk} {
+		if usage != nil {
+			filesystems[usage.Path] = usage.UsedPercent / 100
+		}
+	}
+	if w.filesystems != nil {
+		for path, ratio := range filesystems {
+			old, ok := w.filesystems[path]
+			if !ok {
+				continue
+			}
+			for _, threshold := range w.eventsContext.FilesystemThresholds {
+				if (old < threshold) != (ratio < threshold) {
+					w.publish(Event{Type: FilesystemThresholdCrossed, Time: now, Filesystem: path, Threshold: threshold, Old: old, New: ratio})
+				}
+			}
+		}
+	}
+	w.filesystems = filesystems
+}
+
+func (w *Watcher) publish(event Event) {
+	event.Labels = w.labels
+	w.broker.Publish(event)
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
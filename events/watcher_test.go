@@ -0,0 +1,137 @@
+package events
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeState struct {
+	spec      fetchers.InstanceSpec
+	processes map[string]fetchers.MonitProcessStatus
+	dataUsage float64
+	monitErr  error
+}
+
+func newFakeSnapshots(state *fakeState) *fetchers.Snapshots {
+	return &fetchers.Snapshots{
+		Spec: fetchers.NewSnapshotSource("spec", func(context.Context) (*fetchers.InstanceSpec, error) {
+			spec := state.spec
+			return &spec, nil
+		}),
+		Monit: fetchers.NewSnapshotSource("monit", func(context.Context) (*fetchers.MonitStat, error) {
+			if state.monitErr != nil {
+				return nil, state.monitErr
+			}
+			processes := make(map[string]fetchers.MonitProcessStatus, len(state.processes))
+			for name, process := range state.processes {
+				processes[name] = process
+			}
+			return &fetchers.MonitStat{Processes: processes}, nil
+		}),
+		System: fetchers.NewSnapshotSource("system", func(context.Context) (*fetchers.SystemStat, error) {
+			return &fetchers.SystemStat{Disks: &fetchers.DisksStat{
+				DataDisk: &disk.UsageStat{Path: "/var/vcap/data", UsedPercent: state.dataUsage},
+			}}, nil
+		}),
+	}
+}
+
+func newTestWatcher(state *fakeState) (*Watcher, <-chan Event) {
+	broker := NewBroker()
+	events, _ := broker.Subscribe()
+	eventsContext := &config.EventsContext{FilesystemThresholds: []float64{0.8, 0.9}}
+	labels := map[string]string{"bosh_deployment": "test-dev"}
+	return NewWatcher(eventsContext, newFakeSnapshots(state), broker, labels), events
+}
+
+func drain(events <-chan Event) []Event {
+	var received []Event
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestWatcher_Processes(t *testing.T) {
+	state := &fakeState{processes: map[string]fetchers.MonitProcessStatus{
+		"nats": {Status: "running", MonitoringStatus: "monitored", PID: "42"},
+	}}
+	watcher, events := newTestWatcher(state)
+	watcher.poll(context.Background())
+	assert.Empty(t, drain(events), "the first poll only records the state")
+
+	state.processes["nats"] = fetchers.MonitProcessStatus{Status: "Does not exist", MonitoringStatus: "monitored", PID: "43"}
+	state.processes["bosh-dns"] = fetchers.MonitProcessStatus{Status: "running", MonitoringStatus: "monitored", PID: "7"}
+	watcher.poll(context.Background())
+	received := drain(events)
+	require.Len(t, received, 5)
+	assert.Equal(t, Event{ID: 1, Type: ProcessStatusChanged, Time: received[0].Time, Labels: map[string]string{"bosh_deployment": "test-dev"}, Process: "bosh-dns", Old: "", New: "running"}, received[0])
+	assert.Equal(t, ProcessMonitoringStatusChanged, received[1].Type)
+	assert.Equal(t, ProcessPidChanged, received[2].Type)
+	assert.Equal(t, Event{ID: 4, Type: ProcessStatusChanged, Time: received[3].Time, Labels: map[string]string{"bosh_deployment": "test-dev"}, Process: "nats", Old: "running", New: "Does not exist"}, received[3])
+	assert.Equal(t, Event{ID: 5, Type: ProcessPidChanged, Time: received[4].Time, Labels: map[string]string{"bosh_deployment": "test-dev"}, Process: "nats", Old: "42", New: "43"}, received[4])
+
+	state.monitErr = errors.New("monit is down")
+	watcher.poll(context.Background())
+	received = drain(events)
+	require.Len(t, received, 1, "failed fetches keep the previous state")
+	assert.Equal(t, MonitUnreachable, received[0].Type)
+	assert.Equal(t, "monit is down", received[0].New)
+
+	state.monitErr = nil
+	watcher.poll(context.Background())
+	received = drain(events)
+	require.Len(t, received, 1)
+	assert.Equal(t, MonitReachable, received[0].Type)
+}
+
+func TestWatcher_Spec(t *testing.T) {
+	state := &fakeState{spec: fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats", ID: "b36ca4c9"}}
+	watcher, events := newTestWatcher(state)
+	watcher.poll(context.Background())
+	watcher.poll(context.Background())
+	assert.Empty(t, drain(events))
+
+	state.spec.AZ = "z2"
+	watcher.poll(context.Background())
+	received := drain(events)
+	require.Len(t, received, 1)
+	assert.Equal(t, SpecChanged, received[0].Type)
+	assert.Equal(t, "", received[0].Old.(*fetchers.InstanceSpec).AZ)
+	assert.Equal(t, "z2", received[0].New.(*fetchers.InstanceSpec).AZ)
+}
+
+func TestWatcher_FilesystemThresholds(t *testing.T) {
+	state := &fakeState{dataUsage: 50}
+	watcher, events := newTestWatcher(state)
+	watcher.poll(context.Background())
+
+	state.dataUsage = 95
+	watcher.poll(context.Background())
+	received := drain(events)
+	require.Len(t, received, 2)
+	for i, threshold := range []float64{0.8, 0.9} {
+		assert.Equal(t, FilesystemThresholdCrossed, received[i].Type)
+		assert.Equal(t, "/var/vcap/data", received[i].Filesystem)
+		assert.Equal(t, threshold, received[i].Threshold)
+		assert.Equal(t, 0.5, received[i].Old)
+		assert.Equal(t, 0.95, received[i].New)
+	}
+
+	state.dataUsage = 85
+	watcher.poll(context.Background())
+	received = drain(events)
+	require.Len(t, received, 1)
+	assert.Equal(t, 0.9, received[0].Threshold)
+}
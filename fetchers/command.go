@@ -0,0 +1,51 @@
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// commandWaitDelay bounds the wait for the output pipes after the command was killed,
+// e.g. when a child process keeps them open
+const commandWaitDelay = time.Second
+
+// CommandResult is the outcome of a command run by RunCommand
+type CommandResult struct {
+	Output   []byte        // combined stdout and stderr
+	ExitCode int           // -1 if the command did not exit
+	Duration time.Duration // run time of the command
+}
+
+// RunCommand runs the command with a timeout and captures its combined output.
+// Failed starts, non-zero exit codes and timeouts are returned as errors together with the result,
+// panics are recovered and returned as errors without a result.
+func RunCommand(ctx context.Context, timeout time.Duration, name string, args ...string) (result *CommandResult, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic while running %s: %v", name, r)
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = commandWaitDelay
+	start := time.Now()
+	output, execErr := cmd.CombinedOutput()
+	result = &CommandResult{Output: output, ExitCode: -1, Duration: time.Since(start)}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if execErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result, fmt.Errorf("%s timed out after %s: %w", name, timeout, execErr)
+		}
+		return result, fmt.Errorf("failed to execute %s: %w (output: %s)", name, execErr, strings.TrimSpace(string(output)))
+	}
+	return result, nil
+}
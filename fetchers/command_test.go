@@ -0,0 +1,31 @@
+package fetchers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	result, err := RunCommand(context.Background(), time.Second, "sh", "-c", "echo out; echo err >&2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, string(result.Output), "out")
+	assert.Contains(t, string(result.Output), "err")
+
+	result, err = RunCommand(context.Background(), time.Second, "sh", "-c", "echo failed; exit 3")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed")
+	assert.Equal(t, 3, result.ExitCode)
+
+	result, err = RunCommand(context.Background(), 50*time.Millisecond, "sleep", "5")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Equal(t, -1, result.ExitCode)
+
+	_, err = RunCommand(context.Background(), time.Second, "/nonexistent/command")
+	assert.Error(t, err)
+}
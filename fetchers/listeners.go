@@ -0,0 +1,143 @@
+package fetchers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ListenerProtoTCP  = "tcp"
+	ListenerProtoTCP6 = "tcp6"
+	ListenerProtoUnix = "unix"
+
+	tcpStateListen    = "0A"       // TCP_LISTEN in /proc/net/tcp
+	unixFlagAcceptCon = "00010000" // __SO_ACCEPTCON in /proc/net/unix
+)
+
+// Listener is a listening socket, Port holds the TCP port or the unix socket path
+type Listener struct {
+	Proto string `json:"proto"`
+	Port  string `json:"port"`
+}
+
+// ListenerFetcher reads the listening sockets of processes from the proc filesystem
+type ListenerFetcher struct {
+	procRoot string // /proc
+}
+
+// NewListenerFetcher creates a new ListenerFetcher reading /proc
+func NewListenerFetcher() *ListenerFetcher {
+	return &ListenerFetcher{procRoot: "/proc"}
+}
+
+// Fetch returns the listening sockets of the given PIDs by PID, the listeners of each PID are
+// found by matching the socket inodes of its fd table with the listening sockets of the host.
+// The PIDs with an unreadable fd table are returned apart, their listeners are unknown.
+func (l *ListenerFetcher) Fetch(pids []string) (map[string][]Listener, map[string]error, error) {
+	sockets := make(map[string]Listener)
+	for _, proto := range []string{ListenerProtoTCP, ListenerProtoTCP6} {
+		if err := l.readTCPListeners(proto, sockets); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := l.readUnixListeners(sockets); err != nil {
+		return nil, nil, err
+	}
+
+	result := make(map[string][]Listener, len(pids))
+	unreadable := make(map[string]error)
+	for _, pid := range pids {
+		inodes, err := l.readSocketInodes(pid)
+		if err != nil {
+			unreadable[pid] = err
+			continue
+		}
+		seen := make(map[Listener]bool)
+		for _, inode := range inodes {
+			if listener, ok := sockets[inode]; ok && !seen[listener] {
+				seen[listener] = true
+				result[pid] = append(result[pid], listener)
+			}
+		}
+	}
+	return result, unreadable, nil
+}
+
+// readTCPListeners adds the listening sockets of /proc/net/tcp or /proc/net/tcp6 by inode
+func (l *ListenerFetcher) readTCPListeners(proto string, sockets map[string]Listener) error {
+	path := filepath.Join(l.procRoot, "net", proto)
+	return readProcTable(path, func(fields []string) {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		if len(fields) < 10 || fields[3] != tcpStateListen {
+			return
+		}
+		_, hexPort, found := strings.Cut(fields[1], ":")
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if !found || err != nil {
+			return
+		}
+		sockets[fields[9]] = Listener{Proto: proto, Port: strconv.FormatUint(port, 10)}
+	})
+}
+
+// readUnixListeners adds the listening sockets of /proc/net/unix with a path by inode
+func (l *ListenerFetcher) readUnixListeners(sockets map[string]Listener) error {
+	path := filepath.Join(l.procRoot, "net", "unix")
+	return readProcTable(path, func(fields []string) {
+		// Num RefCount Protocol Flags Type St Inode Path
+		if len(fields) < 8 || fields[3] != unixFlagAcceptCon {
+			return
+		}
+		sockets[fields[6]] = Listener{Proto: ListenerProtoUnix, Port: fields[7]}
+	})
+}
+
+// readSocketInodes returns the inodes of the sockets in the fd table of the PID, none if the process has exited
+func (l *ListenerFetcher) readSocketInodes(pid string) ([]string, error) {
+	fdDir := filepath.Join(l.procRoot, pid, "fd")
+	entries, err := os.ReadDir(fdDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fd table '%s', error: %v", fdDir, err)
+	}
+	var inodes []string
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err != nil {
+			// the fd was closed in the meantime
+			continue
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			inodes = append(inodes, strings.TrimSuffix(inode, "]"))
+		}
+	}
+	return inodes, nil
+}
+
+// readProcTable calls row with the fields of every line of a proc table after the header,
+// a missing table (e.g. tcp6 with IPv6 disabled) is empty
+func readProcTable(path string, row func(fields []string)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open '%s', error: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	for scanner.Scan() {
+		row(strings.Fields(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read '%s', error: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package fetchers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerFetcher_Fetch(t *testing.T) {
+	procRoot := t.TempDir()
+	writeFile := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(procRoot, path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(procRoot, path), []byte(content), 0o644))
+	}
+	writeFile("net/tcp", `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:108E 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:108E 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 2001 1 0000000000000000 100 0 0 10 0
+`)
+	writeFile("net/tcp6", `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:108E 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1003 1 0000000000000000 100 0 0 10 0
+`)
+	writeFile("net/unix", `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 1004 /var/vcap/sys/run/nats/nats.sock
+0000000000000000: 00000003 00000000 00000000 0001 03 1005 /var/vcap/sys/run/nats/nats.sock
+0000000000000000: 00000002 00000000 00010000 0001 01 1006
+`)
+	for pid, targets := range map[string][]string{
+		"100": {"/dev/null", "socket:[1001]", "socket:[1002]", "socket:[1003]", "socket:[1004]", "socket:[1005]", "socket:[1006]"},
+		"200": {"socket:[2001]", "pipe:[3001]"},
+	} {
+		fdDir := filepath.Join(procRoot, pid, "fd")
+		require.NoError(t, os.MkdirAll(fdDir, 0o755))
+		for i, target := range targets {
+			require.NoError(t, os.Symlink(target, filepath.Join(fdDir, string(rune('0'+i)))))
+		}
+	}
+
+	// an fd table which cannot be read does not hide the listeners of the other PIDs
+	writeFile("400/fd", "")
+
+	fetcher := &ListenerFetcher{procRoot: procRoot}
+	listeners, unreadable, err := fetcher.Fetch([]string{"100", "200", "300", "400"})
+	require.NoError(t, err)
+	require.Len(t, unreadable, 1)
+	require.ErrorContains(t, unreadable["400"], "cannot read fd table")
+	require.Equal(t, map[string][]Listener{
+		"100": {
+			{Proto: ListenerProtoTCP, Port: "4238"},
+			{Proto: ListenerProtoTCP6, Port: "4238"},
+			{Proto: ListenerProtoUnix, Port: "/var/vcap/sys/run/nats/nats.sock"},
+		},
+		"200": {{Proto: ListenerProtoTCP, Port: "8080"}},
+	}, listeners)
+}
@@ -3,12 +3,11 @@ package fetchers
 import (
 	"bufio"
 	"context"
-	"errors"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,51 +17,62 @@ type MonitFetcher struct {
 	reBanner  *regexp.Regexp
 	reSection *regexp.Regexp
 	reMetric  *regexp.Regexp
+
+	execCount     atomic.Uint64 // number of monit executions
+	parseDuration atomic.Int64  // duration of the last output parsing in nanoseconds
+	outputBytes   atomic.Int64  // size of the last parsed output
+}
+
+// MonitFetcherStats describes the work done by the MonitFetcher
+type MonitFetcherStats struct {
+	ExecCount     uint64        // number of monit executions (fork/exec)
+	ParseDuration time.Duration // duration of the last output parsing
+	OutputBytes   int64         // size of the last parsed output in bytes
 }
 
 // MonitProcessStatus represents the status of a single process or the system
 type MonitProcessStatus struct {
-	Status           string // service status (e.g., running)
-	MonitoringStatus string // whether monitoring is active (e.g., monitored)
-	PID              string // process ID
-	ParentPID        string // parent process ID
+	Status           string `json:"status"`            // service status (e.g., running)
+	MonitoringStatus string `json:"monitoring_status"` // whether monitoring is active (e.g., monitored)
+	PID              string `json:"pid"`               // process ID
+	ParentPID        string `json:"parent_pid"`        // parent process ID
 
-	Uptime                 time.Duration // uptime since last start
-	Children               int           // number of child processes
-	MemoryUsedBytes        uint64        // memory used in bytes
-	MemoryUsedBytesTotal   uint64        // total memory usage in bytes
-	MemoryUsedPercent      float64       // memory usage as a percentage
-	MemoryUsedPercentTotal float64       // total memory percentage
-	CPUUsedPercent         float64       // CPU usage as a percentage
-	CPUUsedPercentTotal    float64       // total CPU percentage
+	Uptime                 time.Duration `json:"uptime_ns"`                 // uptime since last start
+	Children               int           `json:"children"`                  // number of child processes
+	MemoryUsedBytes        uint64        `json:"memory_used_bytes"`         // memory used in bytes
+	MemoryUsedBytesTotal   uint64        `json:"memory_used_bytes_total"`   // total memory usage in bytes
+	MemoryUsedPercent      float64       `json:"memory_used_percent"`       // memory usage as a percentage
+	MemoryUsedPercentTotal float64       `json:"memory_used_percent_total"` // total memory percentage
+	CPUUsedPercent         float64       `json:"cpu_used_percent"`          // CPU usage as a percentage
+	CPUUsedPercentTotal    float64       `json:"cpu_used_percent_total"`    // total CPU percentage
 
-	DataCollected time.Time // timestamp when data was collected
+	DataCollected time.Time `json:"data_collected"` // timestamp when data was collected
 }
 
 // MonitSystemStatus holds parsed metrics from “bosh monit”
 type MonitSystemStatus struct {
-	Status           string // service status (e.g., "running")
-	MonitoringStatus string // whether monitoring is active (e.g., "monitored")
+	Status           string `json:"status"`            // service status (e.g., "running")
+	MonitoringStatus string `json:"monitoring_status"` // whether monitoring is active (e.g., "monitored")
 
-	LoadAvg1          float64   // 1-minute load average
-	LoadAvg5          float64   // 5-minute load average
-	LoadAvg15         float64   // 15-minute load average
-	CPUUserPercent    float64   // CPU time spent in user mode (%)
-	CPUSystemPercent  float64   // CPU time spent in kernel/system mode (%)
-	CPUIOWaitPercent  float64   // CPU time spent waiting for I/O (%)
-	MemoryUsedBytes   uint64    // memory used in bytes
-	MemoryUsedPercent float64   // memory used as a percentage of total
-	SwapUsedBytes     uint64    // swap used in bytes
-	SwapUsedPercent   float64   // swap used as a percentage of total
-	DataCollected     time.Time // timestamp when data was collected
+	LoadAvg1          float64   `json:"load_avg_1"`          // 1-minute load average
+	LoadAvg5          float64   `json:"load_avg_5"`          // 5-minute load average
+	LoadAvg15         float64   `json:"load_avg_15"`         // 15-minute load average
+	CPUUserPercent    float64   `json:"cpu_user_percent"`    // CPU time spent in user mode (%)
+	CPUSystemPercent  float64   `json:"cpu_system_percent"`  // CPU time spent in kernel/system mode (%)
+	CPUIOWaitPercent  float64   `json:"cpu_iowait_percent"`  // CPU time spent waiting for I/O (%)
+	MemoryUsedBytes   uint64    `json:"memory_used_bytes"`   // memory used in bytes
+	MemoryUsedPercent float64   `json:"memory_used_percent"` // memory used as a percentage of total
+	SwapUsedBytes     uint64    `json:"swap_used_bytes"`     // swap used in bytes
+	SwapUsedPercent   float64   `json:"swap_used_percent"`   // swap used as a percentage of total
+	DataCollected     time.Time `json:"data_collected"`      // timestamp when data was collected
 }
 
 // MonitStat maps process or system names to their status
 type MonitStat struct {
-	Version   string
-	Uptime    time.Duration
-	Processes map[string]MonitProcessStatus
-	System    MonitSystemStatus
+	Version   string                        `json:"version"`
+	Uptime    time.Duration                 `json:"uptime_ns"`
+	Processes map[string]MonitProcessStatus `json:"processes"`
+	System    MonitSystemStatus             `json:"system"`
 }
 
 // NewMonitFetcher creates a new MonitFetcher with the given path to the monit binary
@@ -79,27 +89,38 @@ func NewMonitFetcher(monitPath string) *MonitFetcher {
 }
 
 // Fetch parses the output of `monit status` and returns a MonitStat map
-func (m *MonitFetcher) Fetch(ctx context.Context) (stat *MonitStat, err error) {
-	const timeoutSec = 5
-	ctx, cancel := context.WithTimeout(ctx, timeoutSec*time.Second)
-	defer cancel()
+func (m *MonitFetcher) Fetch(ctx context.Context) (*MonitStat, error) {
+	output, err := m.fetchOutput(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.parse(output)
+}
+
+// fetchOutput runs `monit status` and returns its raw output
+func (m *MonitFetcher) fetchOutput(ctx context.Context) ([]byte, error) {
+	const timeout = 5 * time.Second
+	m.execCount.Add(1)
+	result, err := RunCommand(ctx, timeout, m.monitPath, "status")
+	if err != nil {
+		return nil, err
+	}
+	return result.Output, nil
+}
 
+// parse parses the raw output of `monit status` and records the parsing statistics,
+// panics of the parser are recovered and returned as errors
+func (m *MonitFetcher) parse(output []byte) (stat *MonitStat, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic during Fetch: %v", r)
+			stat, err = nil, fmt.Errorf("panic while parsing monit output: %v", r)
 		}
 	}()
 
-	cmd := exec.CommandContext(ctx, m.monitPath, "status")
-	output, execErr := cmd.CombinedOutput()
-	if execErr != nil {
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			return nil, fmt.Errorf("monit status timed out after %d s: %w", timeoutSec, execErr)
-		}
-		return nil, fmt.Errorf("failed to execute monit: %w (output: %s)", execErr, strings.TrimSpace(string(output)))
-	}
-
+	parseStart := time.Now()
 	stat, parseErr := m.parseData(string(output))
+	m.parseDuration.Store(int64(time.Since(parseStart)))
+	m.outputBytes.Store(int64(len(output)))
 	if parseErr != nil {
 		return nil, fmt.Errorf("failed to parse monit output: %w", parseErr)
 	}
@@ -107,6 +128,15 @@ func (m *MonitFetcher) Fetch(ctx context.Context) (stat *MonitStat, err error) {
 	return stat, nil
 }
 
+// Stats returns the statistics of the work done by the fetcher
+func (m *MonitFetcher) Stats() MonitFetcherStats {
+	return MonitFetcherStats{
+		ExecCount:     m.execCount.Load(),
+		ParseDuration: time.Duration(m.parseDuration.Load()),
+		OutputBytes:   m.outputBytes.Load(),
+	}
+}
+
 func (m *MonitFetcher) parseData(data string) (stat *MonitStat, err error) {
 	stat = &MonitStat{
 		Processes: make(map[string]MonitProcessStatus),
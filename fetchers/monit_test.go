@@ -2,6 +2,8 @@ package fetchers
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,6 +17,39 @@ func TestMonitFetcher_BinNotFound(t *testing.T) {
 	}
 }
 
+func TestMonitFetcher_Stats(t *testing.T) {
+	output := "The Monit daemon 5.2.5 uptime: 8m\n"
+	monitPath := filepath.Join(t.TempDir(), "monit")
+	script := "#!/bin/sh\nprintf '" + output + "'\n"
+	if err := os.WriteFile(monitPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake monit: %v", err)
+	}
+
+	fetcher := NewMonitFetcher(monitPath)
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.Fetch(context.Background())
+		assert.NoError(t, err, "Fetch should complete without error")
+	}
+
+	stats := fetcher.Stats()
+	assert.Equal(t, uint64(2), stats.ExecCount)
+	assert.Equal(t, int64(len("The Monit daemon 5.2.5 uptime: 8m\n")), stats.OutputBytes)
+	assert.Greater(t, int64(stats.ParseDuration), int64(0))
+}
+
+func TestMonitFetcher_ParserPanic(t *testing.T) {
+	monitPath := filepath.Join(t.TempDir(), "monit")
+	if err := os.WriteFile(monitPath, []byte("#!/bin/sh\necho 'The Monit daemon 5.2.5 uptime: 8m'\n"), 0o755); err != nil {
+		t.Fatalf("failed to write fake monit: %v", err)
+	}
+
+	// the fetcher without regular expressions panics in the parser
+	fetcher := &MonitFetcher{monitPath: monitPath}
+	stat, err := fetcher.Fetch(context.Background())
+	assert.Nil(t, stat)
+	assert.ErrorContains(t, err, "panic while parsing monit output")
+}
+
 func TestMonitFetcher_ParsesSuccessfulSampleOutput1(t *testing.T) {
 	sampleOutput := `The Monit daemon 5.2.5 uptime: 19h 17m
 
@@ -0,0 +1,342 @@
+package fetchers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/common"
+	"go.uber.org/zap"
+)
+
+// Layout of a recording: <dir>/<source>/<frame time>/<file>, a frame of a failed fetch holds the error file only
+const (
+	recordingTimeFormat = "20060102T150405.000000000Z"
+	recordingErrorFile  = "error"
+	recordingMonitFile  = "output"
+	recordingSpecFile   = "spec.json"
+	recordingDisksFile  = "disks.json"
+	recordingProcDir    = "proc"
+	recordingSysDir     = "sys"
+)
+
+// recordingSources are the snapshot sources with a frame directory
+var recordingSources = []string{"spec", "monit", "system"}
+
+// recordedProcFiles are the proc files read by the system fetcher through gopsutil
+var recordedProcFiles = []string{"loadavg", "stat", "cpuinfo", "meminfo", "vmstat", "swaps", "zoneinfo"}
+
+// recordedSysGlobs are the CPU topology files used by gopsutil to count the physical cores
+var recordedSysGlobs = []string{
+	"devices/system/cpu/cpu[0-9]*/topology/core_cpus_list",
+	"devices/system/cpu/cpu[0-9]*/topology/thread_siblings_list",
+}
+
+// frameKey is the context key of the frame number of a scrape, see Snapshots.Scrape
+type frameKey struct{}
+
+// scrapeFrame returns the frame number of the scrape the fetch belongs to, false if it does not belong to a scrape
+func scrapeFrame(ctx context.Context) (int64, bool) {
+	frame, ok := ctx.Value(frameKey{}).(int64)
+	return frame, ok
+}
+
+// Recorder saves the raw inputs of every scrape as a timestamped frame: the monit output, the spec.json,
+// the proc files of the system fetcher and the disk usage
+type Recorder struct {
+	dir       string
+	maxFrames int    // frames kept per source, unlimited when 0
+	procRoot  string // /proc
+	sysRoot   string // /sys
+}
+
+func NewRecorder(dir string, maxFrames int) (*Recorder, error) {
+	for _, source := range recordingSources {
+		if err := os.MkdirAll(filepath.Join(dir, source), 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create recording directory '%s', error: %v", dir, err)
+		}
+	}
+	return &Recorder{dir: dir, maxFrames: maxFrames, procRoot: "/proc", sysRoot: "/sys"}, nil
+}
+
+// NewRecordingSnapshots creates synchronous snapshot sources for the given fetchers which record their inputs
+// when fetching for a scrape, the other fetches (alerts, events, API) are not recorded
+func NewRecordingSnapshots(fetchers *Fetchers, recorder *Recorder) *Snapshots {
+	return &Snapshots{
+		Spec: NewSnapshotSource("spec", func(ctx context.Context) (*InstanceSpec, error) {
+			if _, ok := scrapeFrame(ctx); !ok {
+				return fetchers.SpecFetcher.Fetch(ctx)
+			}
+			return recorder.recordSpec(fetchers.SpecFetcher)
+		}),
+		Monit: NewSnapshotSource("monit", func(ctx context.Context) (*MonitStat, error) {
+			if _, ok := scrapeFrame(ctx); !ok {
+				return fetchers.MonitFetcher.Fetch(ctx)
+			}
+			return recorder.recordMonit(ctx, fetchers.MonitFetcher)
+		}),
+		System: NewSnapshotSource("system", func(ctx context.Context) (*SystemStat, error) {
+			if _, ok := scrapeFrame(ctx); !ok {
+				return fetchers.SystemFetcher.Fetch(ctx)
+			}
+			return recorder.recordSystem(ctx, fetchers.SystemFetcher)
+		}),
+		scrapes: &atomic.Int64{},
+	}
+}
+
+func (r *Recorder) recordSpec(fetcher *InstanceSpecFetcher) (*InstanceSpec, error) {
+	data, err := fetcher.fetchRaw()
+	r.record("spec", err, func(frame string) error {
+		return os.WriteFile(filepath.Join(frame, recordingSpecFile), data, 0o644)
+	})
+	if err != nil {
+		return &InstanceSpec{}, err
+	}
+	return fetcher.FetchData(data)
+}
+
+func (r *Recorder) recordMonit(ctx context.Context, fetcher *MonitFetcher) (*MonitStat, error) {
+	output, err := fetcher.fetchOutput(ctx)
+	r.record("monit", err, func(frame string) error {
+		return os.WriteFile(filepath.Join(frame, recordingMonitFile), output, 0o644)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.parse(output)
+}
+
+// recordSystem copies the proc files into the frame before they are parsed, so the frame holds the parsed data
+func (r *Recorder) recordSystem(ctx context.Context, fetcher *SystemFetcher) (*SystemStat, error) {
+	frame, err := r.newFrame("system")
+	if err != nil {
+		zap.L().Warn("Failed to record frame", zap.String("source", "system"), zap.Error(err))
+		return fetcher.Fetch(ctx)
+	}
+	stat, err := r.fetchSystemFrame(ctx, fetcher, frame)
+	if err != nil {
+		r.writeError("system", frame, err)
+	}
+	return stat, err
+}
+
+func (r *Recorder) fetchSystemFrame(ctx context.Context, fetcher *SystemFetcher, frame string) (*SystemStat, error) {
+	if err := r.copyHostFiles(frame); err != nil {
+		return nil, err
+	}
+	disksStat, err := fetcher.fetchDisks(ctx, rootDiskPath, dataDiskPath, storeDiskPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(disksStat)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(frame, recordingDisksFile), data, 0o644); err != nil {
+		return nil, err
+	}
+	return fetcher.fetchSystemWithDisks(withFrameHost(ctx, frame), disksStat)
+}
+
+// record creates a frame of the source and writes the fetch error or the fetched data with write,
+// recording failures are logged and do not fail the fetch
+func (r *Recorder) record(source string, fetchErr error, write func(frame string) error) {
+	frame, err := r.newFrame(source)
+	if err == nil {
+		if fetchErr != nil {
+			r.writeError(source, frame, fetchErr)
+			return
+		}
+		err = write(frame)
+	}
+	if err != nil {
+		zap.L().Warn("Failed to record frame", zap.String("source", source), zap.Error(err))
+	}
+}
+
+// writeError records the error of a failed fetch in the frame
+func (r *Recorder) writeError(source, frame string, fetchErr error) {
+	if err := os.WriteFile(filepath.Join(frame, recordingErrorFile), []byte(fetchErr.Error()), 0o644); err != nil {
+		zap.L().Warn("Failed to record frame", zap.String("source", source), zap.Error(err))
+	}
+}
+
+// newFrame creates the directory of a new frame of the source, the oldest frames beyond maxFrames are removed
+func (r *Recorder) newFrame(source string) (string, error) {
+	sourceDir := filepath.Join(r.dir, source)
+	frame := filepath.Join(sourceDir, time.Now().UTC().Format(recordingTimeFormat))
+	if err := os.Mkdir(frame, 0o755); err != nil {
+		return "", fmt.Errorf("cannot create frame '%s', error: %v", frame, err)
+	}
+	if r.maxFrames > 0 {
+		if err := r.pruneFrames(sourceDir); err != nil {
+			zap.L().Warn("Failed to remove old frames", zap.String("source", source), zap.Error(err))
+		}
+	}
+	return frame, nil
+}
+
+func (r *Recorder) pruneFrames(sourceDir string) error {
+	frames, err := listFrames(sourceDir)
+	if err != nil {
+		return err
+	}
+	for len(frames) > r.maxFrames {
+		if err := os.RemoveAll(filepath.Join(sourceDir, frames[0])); err != nil {
+			return fmt.Errorf("cannot remove frame '%s', error: %v", frames[0], err)
+		}
+		frames = frames[1:]
+	}
+	return nil
+}
+
+// copyHostFiles copies the proc and sys files read by gopsutil into the frame, missing files are skipped
+func (r *Recorder) copyHostFiles(frame string) error {
+	var files [][2]string // source and destination
+	for _, name := range recordedProcFiles {
+		files = append(files, [2]string{filepath.Join(r.procRoot, name), filepath.Join(frame, recordingProcDir, name)})
+	}
+	for _, glob := range recordedSysGlobs {
+		matches, err := filepath.Glob(filepath.Join(r.sysRoot, glob))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			relPath, err := filepath.Rel(r.sysRoot, match)
+			if err != nil {
+				return err
+			}
+			files = append(files, [2]string{match, filepath.Join(frame, recordingSysDir, relPath)})
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file[0])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read '%s', error: %v", file[0], err)
+		}
+		if err := os.MkdirAll(filepath.Dir(file[1]), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file[1], data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replayer serves the frames of a recording, every scrape advances to the next frame of each source
+// and the frames are replayed in a loop
+type Replayer struct {
+	dir    string
+	frames map[string][]string // frame names by source
+}
+
+func NewReplayer(dir string) (*Replayer, error) {
+	r := &Replayer{dir: dir, frames: make(map[string][]string)}
+	for _, source := range recordingSources {
+		frames, err := listFrames(filepath.Join(dir, source))
+		if err != nil {
+			return nil, err
+		}
+		if len(frames) == 0 {
+			return nil, fmt.Errorf("recording directory '%s' has no %s frames", dir, source)
+		}
+		r.frames[source] = frames
+	}
+	return r, nil
+}
+
+// NewReplaySnapshots creates synchronous snapshot sources parsing the recorded inputs with the given fetchers,
+// the fetches which do not belong to a scrape (alerts, events, API) replay the frame of the latest scrape
+func NewReplaySnapshots(fetchers *Fetchers, replayer *Replayer) *Snapshots {
+	scrapes := &atomic.Int64{}
+	frame := func(ctx context.Context, source string) string {
+		n, ok := scrapeFrame(ctx)
+		if !ok {
+			n = max(scrapes.Load()-1, 0)
+		}
+		return replayer.frame(source, n)
+	}
+	return &Snapshots{
+		Spec: NewSnapshotSource("spec", func(ctx context.Context) (*InstanceSpec, error) {
+			data, err := readFrameFile(frame(ctx, "spec"), recordingSpecFile)
+			if err != nil {
+				return &InstanceSpec{}, err
+			}
+			return fetchers.SpecFetcher.FetchData(data)
+		}),
+		Monit: NewSnapshotSource("monit", func(ctx context.Context) (*MonitStat, error) {
+			output, err := readFrameFile(frame(ctx, "monit"), recordingMonitFile)
+			if err != nil {
+				return nil, err
+			}
+			return fetchers.MonitFetcher.parse(output)
+		}),
+		System: NewSnapshotSource("system", func(ctx context.Context) (*SystemStat, error) {
+			frame := frame(ctx, "system")
+			data, err := readFrameFile(frame, recordingDisksFile)
+			if err != nil {
+				return nil, err
+			}
+			var disksStat DisksStat
+			if err := json.Unmarshal(data, &disksStat); err != nil {
+				return nil, fmt.Errorf("cannot parse '%s', error: %v", filepath.Join(frame, recordingDisksFile), err)
+			}
+			return fetchers.SystemFetcher.fetchSystemWithDisks(withFrameHost(ctx, frame), &disksStat)
+		}),
+		scrapes: scrapes,
+	}
+}
+
+// frame returns the path of the frame of the source replayed by the scrape with the given number
+func (r *Replayer) frame(source string, n int64) string {
+	frames := r.frames[source]
+	return filepath.Join(r.dir, source, frames[n%int64(len(frames))])
+}
+
+// readFrameFile returns the content of a frame file, or the recorded error of a failed fetch
+func readFrameFile(frame, name string) ([]byte, error) {
+	if message, err := os.ReadFile(filepath.Join(frame, recordingErrorFile)); err == nil {
+		return nil, errors.New(strings.TrimSpace(string(message)))
+	}
+	data, err := os.ReadFile(filepath.Join(frame, name))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read recorded file, error: %v", err)
+	}
+	return data, nil
+}
+
+// listFrames returns the sorted frame names of a source directory, the names sort by time
+func listFrames(sourceDir string) ([]string, error) {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read recording directory '%s', error: %v", sourceDir, err)
+	}
+	var frames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			frames = append(frames, entry.Name())
+		}
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
+
+// withFrameHost makes gopsutil read the proc and sys files of the frame
+func withFrameHost(ctx context.Context, frame string) context.Context {
+	return context.WithValue(ctx, common.EnvKey, common.EnvMap{
+		common.HostProcEnvKey: filepath.Join(frame, recordingProcDir),
+		common.HostSysEnvKey:  filepath.Join(frame, recordingSysDir),
+	})
+}
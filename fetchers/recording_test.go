@@ -0,0 +1,118 @@
+package fetchers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const recordedMonitOutput = `The Monit daemon 5.2.5 uptime: 19h 17m
+
+Process 'nats'
+  status                            running
+  monitoring status                 monitored
+  pid                               4242
+`
+
+func TestRecorderAndReplayer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the system fetcher is recorded from /proc")
+	}
+	tmp := t.TempDir()
+	specPath := filepath.Join(tmp, "spec.json")
+	require.NoError(t, os.WriteFile(specPath, []byte(`{"deployment": "test-dev", "name": "nats", "index": 1}`), 0o644))
+	monitPath := filepath.Join(tmp, "monit")
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "monit-output"), []byte(recordedMonitOutput), 0o644))
+	require.NoError(t, os.WriteFile(monitPath, []byte("#!/bin/sh\ncat "+filepath.Join(tmp, "monit-output")+"\n"), 0o755))
+
+	recordingDir := filepath.Join(tmp, "recording")
+	recorder, err := NewRecorder(recordingDir, 2)
+	require.NoError(t, err)
+	recorder.sysRoot = filepath.Join(tmp, "no-sys")
+	ctx := context.Background()
+	live := NewRecordingSnapshots(NewFetchers(specPath, monitPath), recorder)
+
+	// the fetches which do not belong to a scrape are not recorded
+	spec := live.Spec.Get(ctx)
+	require.NoError(t, spec.Err)
+	var monitSnapshots []Snapshot[MonitStat]
+	for range 3 {
+		monitSnapshots = append(monitSnapshots, live.Monit.Get(live.Scrape(ctx)))
+		live.Monit.Get(ctx)
+	}
+	// the frame of the fourth scrape records a failed fetch
+	require.NoError(t, os.Chmod(monitPath, 0o644))
+	scrape := live.Scrape(ctx)
+	failed := live.Monit.Get(scrape)
+	require.Error(t, failed.Err)
+	system := live.System.Get(scrape)
+	require.NoError(t, system.Err)
+
+	monitFrames, err := listFrames(filepath.Join(recordingDir, "monit"))
+	require.NoError(t, err)
+	assert.Len(t, monitFrames, 2, "the oldest frames are removed")
+
+	replayer, err := NewReplayer(recordingDir)
+	require.NoError(t, err)
+	replayed := NewReplaySnapshots(NewFetchers("unused", "unused"), replayer)
+
+	scrape = replayed.Scrape(ctx)
+	replayedSpec := replayed.Spec.Get(scrape)
+	require.NoError(t, replayedSpec.Err)
+	assert.Equal(t, spec.Value, replayedSpec.Value)
+	replayedMonit := replayed.Monit.Get(scrape)
+	require.NoError(t, replayedMonit.Err)
+	assert.Equal(t, monitSnapshots[2].Value, replayedMonit.Value)
+	replayedSystem := replayed.System.Get(scrape)
+	require.NoError(t, replayedSystem.Err)
+	assert.Equal(t, system.Value, replayedSystem.Value)
+
+	replayedMonit = replayed.Monit.Get(replayed.Scrape(ctx))
+	assert.EqualError(t, replayedMonit.Err, failed.Err.Error())
+	replayedMonit = replayed.Monit.Get(ctx)
+	assert.EqualError(t, replayedMonit.Err, failed.Err.Error(), "the other fetches replay the frame of the latest scrape")
+	replayedMonit = replayed.Monit.Get(replayed.Scrape(ctx))
+	assert.NoError(t, replayedMonit.Err, "the frames are replayed in a loop")
+}
+
+func TestRecordingSnapshots_FramePerScrape(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the system fetcher is recorded from /proc")
+	}
+	tmp := t.TempDir()
+	specPath := filepath.Join(tmp, "spec.json")
+	require.NoError(t, os.WriteFile(specPath, []byte(`{"deployment": "test-dev", "name": "nats", "index": 1}`), 0o644))
+	monitPath := filepath.Join(tmp, "monit")
+	require.NoError(t, os.WriteFile(monitPath, []byte("#!/bin/sh\necho 'The Monit daemon 5.2.5 uptime: 1m'\n"), 0o755))
+	recordingDir := filepath.Join(tmp, "recording")
+	recorder, err := NewRecorder(recordingDir, 0)
+	require.NoError(t, err)
+	snapshots := NewRecordingSnapshots(NewFetchers(specPath, monitPath), recorder)
+
+	const scrapes = 3
+	ctx := context.Background()
+	for range scrapes {
+		scrape := snapshots.Scrape(ctx)
+		snapshots.Monit.Get(scrape)
+		snapshots.System.Get(scrape)
+		// alerts, events and API requests in between
+		snapshots.Spec.Get(ctx)
+		snapshots.Monit.Get(ctx)
+		snapshots.System.Get(ctx)
+	}
+	for _, source := range recordingSources {
+		frames, err := listFrames(filepath.Join(recordingDir, source))
+		require.NoError(t, err)
+		assert.Len(t, frames, scrapes, source)
+	}
+}
+
+func TestNewReplayer_Empty(t *testing.T) {
+	_, err := NewReplayer(t.TempDir())
+	require.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package fetchers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Snapshot holds the latest successfully fetched value and the error of the latest fetch attempt
+type Snapshot[T any] struct {
+	Value *T        // latest successfully fetched value, nil if no fetch has succeeded yet
+	Time  time.Time // time when Value was fetched
+	Err   error     // error of the latest fetch attempt, nil if it succeeded
+}
+
+// Age returns how old the snapshot value is at the given time
+func (s Snapshot[T]) Age(now time.Time) time.Duration {
+	return now.Sub(s.Time)
+}
+
+// SnapshotSource wraps a fetch function and keeps its latest snapshot.
+// In synchronous mode every Get fetches, concurrent calls share a single in-flight fetch.
+// In background mode the snapshot is refreshed by Run and Get serves the cached one.
+type SnapshotSource[T any] struct {
+	name  string
+	fetch func(ctx context.Context) (*T, error)
+
+	mu            sync.Mutex
+	last          Snapshot[T]
+	fetched       bool
+	inflight      chan struct{}
+	inflightFrame any // scrape frame of the in-flight fetch, only fetches of the same frame are shared
+	background    bool
+
+	joined func() // called when a Get joins the in-flight fetch, set by tests
+}
+
+// NewSnapshotSource creates a synchronous SnapshotSource for the given fetch function
+func NewSnapshotSource[T any](name string, fetch func(ctx context.Context) (*T, error)) *SnapshotSource[T] {
+	return &SnapshotSource[T]{name: name, fetch: fetch}
+}
+
+// Name returns the name of the source
+func (s *SnapshotSource[T]) Name() string {
+	return s.name
+}
+
+// Get returns the cached snapshot in background mode, otherwise it fetches a new one
+func (s *SnapshotSource[T]) Get(ctx context.Context) Snapshot[T] {
+	s.mu.Lock()
+	if s.background && s.fetched {
+		last := s.last
+		s.mu.Unlock()
+		return last
+	}
+	frame := ctx.Value(frameKey{})
+	if done := s.inflight; done != nil && s.inflightFrame == frame {
+		joined := s.joined
+		s.mu.Unlock()
+		if joined != nil {
+			joined()
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return Snapshot[T]{Err: ctx.Err()}
+		}
+		return s.Last()
+	}
+	done := make(chan struct{})
+	s.inflight = done
+	s.inflightFrame = frame
+	s.mu.Unlock()
+
+	s.refresh(ctx)
+
+	s.mu.Lock()
+	if s.inflight == done {
+		s.inflight = nil
+	}
+	last := s.last
+	s.mu.Unlock()
+	close(done)
+	return last
+}
+
+// Last returns the latest snapshot without fetching
+func (s *SnapshotSource[T]) Last() Snapshot[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Run switches the source to background mode and refreshes the snapshot every interval until ctx is done
+func (s *SnapshotSource[T]) Run(ctx context.Context, interval time.Duration) {
+	s.mu.Lock()
+	s.background = true
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SnapshotSource[T]) refresh(ctx context.Context) {
+	value, err := s.fetch(ctx)
+	now := time.Now()
+	if err != nil {
+		zap.L().Debug("Snapshot fetch failed", zap.String("source", s.name), zap.Error(err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = true
+	s.last.Err = err
+	if err == nil {
+		s.last.Value = value
+		s.last.Time = now
+	}
+}
+
+// Snapshots gives access to the latest results of all fetchers
+type Snapshots struct {
+	Spec   *SnapshotSource[InstanceSpec]
+	Monit  *SnapshotSource[MonitStat]
+	System *SnapshotSource[SystemStat]
+
+	scrapes *atomic.Int64 // number of scrapes in record and replay mode, nil otherwise
+}
+
+// Scrape returns the context of the fetches of a scrape. In record and replay mode the fetches of a scrape
+// record or replay a single frame of every source, the spec is fetched here as it is only read at startup otherwise.
+func (s *Snapshots) Scrape(ctx context.Context) context.Context {
+	if s.scrapes == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, frameKey{}, s.scrapes.Add(1)-1)
+	s.Spec.Get(ctx)
+	return ctx
+}
+
+// NewSnapshots creates synchronous snapshot sources for the given fetchers
+func NewSnapshots(fetchers *Fetchers) *Snapshots {
+	return &Snapshots{
+		Spec:   NewSnapshotSource("spec", fetchers.SpecFetcher.Fetch),
+		Monit:  NewSnapshotSource("monit", fetchers.MonitFetcher.Fetch),
+		System: NewSnapshotSource("system", fetchers.SystemFetcher.Fetch),
+	}
+}
+
+// Run refreshes the Monit and system snapshots in background goroutines every interval until ctx is done.
+// The spec is not refreshed, the instance labels of the collectors are derived from the spec read at startup.
+func (s *Snapshots) Run(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); s.Monit.Run(ctx, interval) }()
+	go func() { defer wg.Done(); s.System.Run(ctx, interval) }()
+	wg.Wait()
+}
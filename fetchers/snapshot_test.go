@@ -0,0 +1,100 @@
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotSource_SingleFlight(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	source := NewSnapshotSource("test", func(ctx context.Context) (*int, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		value := 42
+		return &value, nil
+	})
+	joined := make(chan struct{}, 4)
+	source.joined = func() { joined <- struct{}{} }
+
+	var wg sync.WaitGroup
+	results := make(chan Snapshot[int], 5)
+	get := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- source.Get(context.Background())
+		}()
+	}
+	get()
+	<-started
+	for i := 0; i < 4; i++ {
+		get()
+	}
+	// release the fetch only once all other callers wait for it
+	for i := 0; i < 4; i++ {
+		<-joined
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), calls.Load())
+	for snapshot := range results {
+		assert.NoError(t, snapshot.Err)
+		assert.Equal(t, 42, *snapshot.Value)
+	}
+}
+
+func TestSnapshotSource_KeepsLastValueOnError(t *testing.T) {
+	fail := false
+	source := NewSnapshotSource("test", func(ctx context.Context) (*int, error) {
+		if fail {
+			return nil, errors.New("fetch failed")
+		}
+		value := 1
+		return &value, nil
+	})
+
+	first := source.Get(context.Background())
+	assert.NoError(t, first.Err)
+
+	fail = true
+	second := source.Get(context.Background())
+	assert.Error(t, second.Err)
+	assert.Equal(t, 1, *second.Value)
+	assert.Equal(t, first.Time, second.Time)
+}
+
+func TestSnapshotSource_Background(t *testing.T) {
+	var calls atomic.Int32
+	source := NewSnapshotSource("test", func(ctx context.Context) (*int32, error) {
+		value := calls.Add(1)
+		return &value, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		source.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	// in background mode Get serves the cached snapshot without fetching
+	fetched := calls.Load()
+	snapshot := source.Get(context.Background())
+	assert.NoError(t, snapshot.Err)
+	assert.Equal(t, fetched, *snapshot.Value)
+	assert.Equal(t, fetched, calls.Load())
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 )
 
 // InstanceSpecFetcher holds BOSH instance metadata from the spec.json
@@ -13,11 +15,39 @@ type InstanceSpecFetcher struct {
 }
 
 type InstanceSpec struct {
-	Deployment string `json:"deployment"`
-	Name       string `json:"name"`
-	Index      int    `json:"index"`
-	ID         string `json:"id"`
-	AZ         string `json:"az"`
+	Deployment string                 `json:"deployment"`
+	Name       string                 `json:"name"`
+	Index      int                    `json:"index"`
+	ID         string                 `json:"id"`
+	AZ         string                 `json:"az"`
+	Networks   map[string]NetworkSpec `json:"networks"`
+}
+
+// NetworkSpec holds the address of the instance on a Bosh network
+type NetworkSpec struct {
+	IP      string   `json:"ip"`
+	Default []string `json:"default,omitempty"` // properties taken from this network, e.g. dns, gateway
+}
+
+// IP returns the address of the instance on the network providing the default gateway,
+// or on the first network by name if no network is marked as default
+func (s *InstanceSpec) IP() string {
+	names := make([]string, 0, len(s.Networks))
+	for name := range s.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if network := s.Networks[name]; network.IP != "" && slices.Contains(network.Default, "gateway") {
+			return network.IP
+		}
+	}
+	for _, name := range names {
+		if network := s.Networks[name]; network.IP != "" {
+			return network.IP
+		}
+	}
+	return ""
 }
 
 func NewInstanceSpecFetcher(specPath string) *InstanceSpecFetcher {
@@ -25,14 +55,22 @@ func NewInstanceSpecFetcher(specPath string) *InstanceSpecFetcher {
 }
 
 func (m *InstanceSpecFetcher) Fetch(_ context.Context) (*InstanceSpec, error) {
-	var spec InstanceSpec
-	data, err := os.ReadFile(m.specPath)
+	data, err := m.fetchRaw()
 	if err != nil {
-		return &spec, fmt.Errorf("cannot read instance spec file '%s', error: %v", m.specPath, err)
+		return &InstanceSpec{}, err
 	}
 	return m.FetchData(data)
 }
 
+// fetchRaw returns the content of the spec.json
+func (m *InstanceSpecFetcher) fetchRaw() ([]byte, error) {
+	data, err := os.ReadFile(m.specPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read instance spec file '%s', error: %v", m.specPath, err)
+	}
+	return data, nil
+}
+
 func (m *InstanceSpecFetcher) FetchData(data []byte) (*InstanceSpec, error) {
 	var spec InstanceSpec
 	err := json.Unmarshal(data, &spec)
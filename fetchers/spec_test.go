@@ -45,3 +45,30 @@ func TestInstanceSpecCollector_FileNotFound(t *testing.T) {
 		t.Error("expected error for missing file, got nil")
 	}
 }
+
+func TestInstanceSpec_IP(t *testing.T) {
+	data := []byte(`{
+	"deployment": "test-dev",
+	"networks": {
+		"backend": {"ip": "10.0.2.5", "netmask": "255.255.255.0"},
+		"default": {"ip": "10.0.1.5", "netmask": "255.255.255.0", "default": ["dns", "gateway"]}
+	}
+}`)
+	spec, err := NewInstanceSpecFetcher("fake-path").FetchData(data)
+	if err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if ip := spec.IP(); ip != "10.0.1.5" {
+		t.Errorf("expected IP of the gateway network '10.0.1.5', got '%s'", ip)
+	}
+
+	delete(spec.Networks, "default")
+	if ip := spec.IP(); ip != "10.0.2.5" {
+		t.Errorf("expected IP of the only network '10.0.2.5', got '%s'", ip)
+	}
+
+	spec.Networks = nil
+	if ip := spec.IP(); ip != "" {
+		t.Errorf("expected no IP without networks, got '%s'", ip)
+	}
+}
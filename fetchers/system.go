@@ -13,33 +13,40 @@ import (
 type SystemFetcher struct{}
 
 type HostStat struct {
-	Load *load.AvgStat
+	Load *load.AvgStat `json:"load"`
 }
 
 type CPUStat struct {
-	LogicalCores  int // number of logical CPU cores
-	PhysicalCores int // number of logical CPU cores
+	LogicalCores  int `json:"logical_cores"`  // number of logical CPU cores
+	PhysicalCores int `json:"physical_cores"` // number of logical CPU cores
 }
 
 type MemoryStat struct {
-	VM         *mem.VirtualMemoryStat // virtual memory statistics
-	SwapMemory *mem.SwapMemoryStat    // swap memory
+	VM         *mem.VirtualMemoryStat `json:"vm"`   // virtual memory statistics
+	SwapMemory *mem.SwapMemoryStat    `json:"swap"` // swap memory
 }
 
 type DisksStat struct {
-	RootDisk  *disk.UsageStat // root disk usage
-	DataDisk  *disk.UsageStat // /var/vcap/data disk usage
-	StoreDisk *disk.UsageStat // /var/vcap/store disk usage
+	RootDisk  *disk.UsageStat `json:"root"`  // root disk usage
+	DataDisk  *disk.UsageStat `json:"data"`  // /var/vcap/data disk usage
+	StoreDisk *disk.UsageStat `json:"store"` // /var/vcap/store disk usage
 }
 
 // SystemStat holds metrics about disk, memory, and CPU usage
 type SystemStat struct {
-	Host   *HostStat
-	CPU    *CPUStat
-	Memory *MemoryStat
-	Disks  *DisksStat
+	Host   *HostStat   `json:"host"`
+	CPU    *CPUStat    `json:"cpu"`
+	Memory *MemoryStat `json:"memory"`
+	Disks  *DisksStat  `json:"disks"`
 }
 
+// Mount points of the Bosh instance disks
+const (
+	rootDiskPath  = "/"
+	dataDiskPath  = "/var/vcap/data"
+	storeDiskPath = "/var/vcap/store"
+)
+
 // NewSystemFetcher initializes a new SystemFetcher
 func NewSystemFetcher() *SystemFetcher {
 	return &SystemFetcher{}
@@ -47,24 +54,30 @@ func NewSystemFetcher() *SystemFetcher {
 
 // Fetch retrieves current system metrics and returns them
 func (m *SystemFetcher) Fetch(ctx context.Context) (*SystemStat, error) {
-	return m.fetchSystem(ctx, "/", "/var/vcap/data", "/var/vcap/store")
+	return m.fetchSystem(ctx, rootDiskPath, dataDiskPath, storeDiskPath)
 }
 
-// Fetch retrieves current system metrics and returns them
+// fetchSystem retrieves current system metrics and the usage of the disks mounted at the given paths
 func (m *SystemFetcher) fetchSystem(ctx context.Context, rootDiskPath, dataDiskPath, storeDiskPath string) (*SystemStat, error) {
-	hostStat, err := m.fetchHost(ctx)
+	disksStat, err := m.fetchDisks(ctx, rootDiskPath, dataDiskPath, storeDiskPath)
 	if err != nil {
 		return nil, err
 	}
-	cpuStat, err := m.fetchCPU(ctx)
+	return m.fetchSystemWithDisks(ctx, disksStat)
+}
+
+// fetchSystemWithDisks retrieves the host, CPU and memory metrics from the proc filesystem, which can be
+// relocated with the gopsutil environment of ctx, and adds the given disk usage
+func (m *SystemFetcher) fetchSystemWithDisks(ctx context.Context, disksStat *DisksStat) (*SystemStat, error) {
+	hostStat, err := m.fetchHost(ctx)
 	if err != nil {
 		return nil, err
 	}
-	memoryStat, err := m.fetchMemory(ctx)
+	cpuStat, err := m.fetchCPU(ctx)
 	if err != nil {
 		return nil, err
 	}
-	disksStat, err := m.fetchDisks(ctx, rootDiskPath, dataDiskPath, storeDiskPath)
+	memoryStat, err := m.fetchMemory(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"boshi_exporter/alerts"
+	"boshi_exporter/api"
 	"boshi_exporter/collectors"
 	"boshi_exporter/config"
+	"boshi_exporter/events"
 	"boshi_exporter/fetchers"
+	"boshi_exporter/peers"
+	"boshi_exporter/sinks"
+	"context"
+	"errors"
 	"github.com/prometheus/client_golang/prometheus"
+	promcollectors "github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -18,6 +30,8 @@ var ProgramVersion = "dev"
 const (
 	ProgramName = "boshi_exporter"
 	ProgramHelp = "Bosh Instance Exporter"
+
+	shutdownTimeout = 5 * time.Second
 )
 
 func initLogger(logLevel, logPath string) *zap.Logger {
@@ -36,26 +50,286 @@ func initLogger(logLevel, logPath string) *zap.Logger {
 	return logger
 }
 
-func createPromHttpHandler(metricsContext *config.MetricsContext, fetchers *fetchers.Fetchers) (http.Handler, error) {
+// stderrLogPath redirects logging to stdout to stderr, for subcommands writing their output to stdout
+func stderrLogPath(logPath string) string {
+	if logPath == "stdout" {
+		return "stderr"
+	}
+	return logPath
+}
+
+// createSnapshots creates the snapshot sources of the fetchers, recording their inputs or replaying a recording if configured
+func createSnapshots(cfg *config.Config, allFetchers *fetchers.Fetchers) (*fetchers.Snapshots, error) {
+	switch {
+	case *cfg.RecordDir != "" && *cfg.ReplayDir != "":
+		return nil, errors.New("--record.dir and --replay.dir cannot be combined")
+	case *cfg.RecordDir != "":
+		recorder, err := fetchers.NewRecorder(*cfg.RecordDir, *cfg.RecordMaxFrames)
+		if err != nil {
+			return nil, err
+		}
+		return fetchers.NewRecordingSnapshots(allFetchers, recorder), nil
+	case *cfg.ReplayDir != "":
+		replayer, err := fetchers.NewReplayer(*cfg.ReplayDir)
+		if err != nil {
+			return nil, err
+		}
+		return fetchers.NewReplaySnapshots(allFetchers, replayer), nil
+	default:
+		return fetchers.NewSnapshots(allFetchers), nil
+	}
+}
+
+// createRegistry registers the Bosh instance collector and the extra collectors in a new registry
+func createRegistry(metricsContext *config.MetricsContext, snapshots *fetchers.Snapshots, extraCollectors ...prometheus.Collector) (*prometheus.Registry, *collectors.BoshInstanceCollector, error) {
 	registry := prometheus.NewRegistry()
-	collector, err := collectors.NewBoshInstanceCollector(ProgramName, ProgramVersion, metricsContext, fetchers)
+	collector, err := collectors.NewBoshInstanceCollector(ProgramName, ProgramVersion, metricsContext, snapshots)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	registry.MustRegister(collector)
-	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-	return handler, nil
+	registry.MustRegister(extraCollectors...)
+	return registry, collector, nil
+}
+
+func createPromHttpHandler(gatherer prometheus.Gatherer, collector *collectors.BoshInstanceCollector, extraCollectors []prometheus.Collector, groupCollectors map[string]prometheus.Collector) http.Handler {
+	handler := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	return newFilteringHandler(collector, extraCollectors, groupCollectors, handler)
+}
+
+// createGroupCollectors returns the configured collectors of the metric groups served outside of the
+// BoshInstanceCollector, by group name, the textfiles must not repeat the metrics of the extra collectors
+func createGroupCollectors(cfg *config.Config, metricsContext *config.MetricsContext, snapshots *fetchers.Snapshots, collector *collectors.BoshInstanceCollector, extraCollectors []prometheus.Collector) (map[string]prometheus.Collector, error) {
+	collectorsConfig, err := config.LoadCollectorsConfig(*cfg.CollectorConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	groupCollectors := map[string]prometheus.Collector{
+		collectors.ListenerCollectorName: collectors.NewListenerCollector(metricsContext, collector.InstanceLabels(), snapshots.Monit, collectorsConfig.Listeners),
+	}
+	if *cfg.TextfileDirectory != "" {
+		groupCollectors[collectors.TextfileCollectorName] = collectors.NewTextfileCollector(metricsContext, collector.InstanceLabels(), *cfg.TextfileDirectory, extraCollectors)
+	}
+	if len(collectorsConfig.Exec) > 0 {
+		groupCollectors[collectors.ExecCollectorName] = collectors.NewExecCollector(metricsContext, collector.InstanceLabels(), collectorsConfig.Exec)
+	}
+	if len(collectorsConfig.Probes) > 0 {
+		probeCollector, err := collectors.NewProbeCollector(metricsContext, collector.InstanceLabels(), collectorsConfig.Probes)
+		if err != nil {
+			return nil, err
+		}
+		groupCollectors[collectors.ProbeCollectorName] = probeCollector
+	}
+	return groupCollectors, nil
+}
+
+// startSinks starts pushing the registry metrics to all configured sinks in background goroutines,
+// the returned wait group is done when all sinks have been shut down
+func startSinks(ctx context.Context, cfg *config.Config, metricsContext *config.MetricsContext, registry *prometheus.Registry, collector *collectors.BoshInstanceCollector) (*sync.WaitGroup, error) {
+	wg := &sync.WaitGroup{}
+	run := func(sink sinks.Sink, interval time.Duration) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sinks.Run(ctx, sink, registry, interval, shutdownTimeout)
+		}()
+	}
+	if *cfg.RemoteWriteURL != "" {
+		remoteWriteCtx := cfg.CreateRemoteWriteContext()
+		sink, err := sinks.NewRemoteWriteSink(remoteWriteCtx)
+		if err != nil {
+			return nil, err
+		}
+		run(sink, remoteWriteCtx.Interval)
+	}
+	if *cfg.OTLPEndpoint != "" {
+		otlpCtx := cfg.CreateOTLPContext()
+		resource := sinks.NewResourceAttributes(metricsContext, collector.InstanceSpec())
+		var instanceLabelNames []string
+		for name := range collector.InstanceLabels() {
+			instanceLabelNames = append(instanceLabelNames, name)
+		}
+		sink, err := sinks.NewOTLPSink(otlpCtx, resource, instanceLabelNames, ProgramName, ProgramVersion)
+		if err != nil {
+			return nil, err
+		}
+		run(sink, otlpCtx.Interval)
+	}
+	if *cfg.PushGatewayURL != "" {
+		pushgatewayCtx := cfg.CreatePushgatewayContext()
+		sink, err := sinks.NewPushgatewaySink(pushgatewayCtx, collectors.NewGroupingLabels(metricsContext, collector.InstanceSpec()))
+		if err != nil {
+			return nil, err
+		}
+		run(sink, pushgatewayCtx.Interval)
+	}
+	if *cfg.StatsDAddress != "" {
+		statsdCtx := cfg.CreateStatsDContext()
+		sink, err := sinks.NewStatsDSink(statsdCtx)
+		if err != nil {
+			return nil, err
+		}
+		run(sink, statsdCtx.Interval)
+	}
+	if *cfg.InfluxURL != "" {
+		influxCtx := cfg.CreateInfluxContext()
+		sink, err := sinks.NewInfluxSink(influxCtx)
+		if err != nil {
+			return nil, err
+		}
+		run(sink, influxCtx.Interval)
+	}
+	if *cfg.GraphiteAddress != "" {
+		graphiteCtx := cfg.CreateGraphiteContext()
+		sink, err := sinks.NewGraphiteSink(graphiteCtx)
+		if err != nil {
+			return nil, err
+		}
+		run(sink, graphiteCtx.Interval)
+	}
+	return wg, nil
+}
+
+// startAlerts starts evaluating the alert rules on the registry metrics in a background goroutine
+func startAlerts(ctx context.Context, alertsContext *config.AlertsContext, metricsContext *config.MetricsContext, registry *prometheus.Registry) error {
+	if len(alertsContext.AlertmanagerURLs) == 0 {
+		return errors.New("alert rules require at least one --alerts.alertmanager-url")
+	}
+	rules, err := alerts.LoadRules(alertsContext.RulesFile)
+	if err != nil {
+		return err
+	}
+	evaluator, err := alerts.NewEvaluator(alertsContext, rules, collectors.VolatileLabelNames(metricsContext))
+	if err != nil {
+		return err
+	}
+	go evaluator.Run(ctx, registry)
+	return nil
+}
+
+// createSelfCollectors returns the collectors of the exporter's own Go runtime, process and work metrics
+func createSelfCollectors(metricsContext *config.MetricsContext, fetchers *fetchers.Fetchers) []prometheus.Collector {
+	return []prometheus.Collector{
+		promcollectors.NewGoCollector(),
+		promcollectors.NewProcessCollector(promcollectors.ProcessCollectorOpts{}),
+		collectors.NewExporterCollector(metricsContext, fetchers.MonitFetcher),
+	}
+}
+
+// newFilteringHandler serves scrapes with collect[] or exclude[] parameters from a per-request registry
+// holding a filtered collector, the selected group collectors and the extra collectors,
+// all other scrapes are served by the default handler
+func newFilteringHandler(collector *collectors.BoshInstanceCollector, extraCollectors []prometheus.Collector, groupCollectors map[string]prometheus.Collector, defaultHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		collect := query["collect[]"]
+		exclude := query["exclude[]"]
+		if len(collect) == 0 && len(exclude) == 0 {
+			defaultHandler.ServeHTTP(w, r)
+			return
+		}
+		filtered, err := collector.Filter(collect, exclude)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		selected := append([]prometheus.Collector{filtered}, extraCollectors...)
+		for name, c := range groupCollectors {
+			if filtered.Enabled(name) {
+				selected = append(selected, c)
+			}
+		}
+		registry := prometheus.NewRegistry()
+		for _, c := range selected {
+			if err := registry.Register(c); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
 }
 
 func main() {
 	cfg := config.ParseConfig(ProgramName, ProgramHelp, ProgramVersion)
+	if cfg.Command == config.CheckCommand || cfg.Command == config.DumpCommand {
+		// stdout is reserved for the command output
+		logger := initLogger(*cfg.LogLevel, stderrLogPath(*cfg.LogPath))
+		run := runCheck
+		if cfg.Command == config.DumpCommand {
+			run = runDump
+		}
+		code := run(cfg)
+		_ = logger.Sync()
+		os.Exit(code)
+	}
+	if cfg.Command == config.SimulateCommand {
+		logger := initLogger(*cfg.LogLevel, *cfg.LogPath)
+		code := runSimulate(cfg)
+		_ = logger.Sync()
+		os.Exit(code)
+	}
 	logger := initLogger(*cfg.LogLevel, *cfg.LogPath)
 	defer func() { _ = logger.Sync() }()
-	metricsCtx := cfg.CreateMetricsContext()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	metricsCtx, err := cfg.CreateMetricsContext(collectors.LabelNames)
+	if err != nil {
+		zap.L().Error("Invalid metrics configuration", zap.Error(err))
+		os.Exit(1)
+	}
+	if (*cfg.RecordDir != "" || *cfg.ReplayDir != "") && *cfg.CollectorInterval > 0 {
+		zap.L().Error("The record and replay modes work per scrape, they cannot be combined with --collector.interval")
+		os.Exit(1)
+	}
 	allFetchers := fetchers.NewFetchers(*cfg.BoshSpecPath, *cfg.MonitPath)
-	handler, err := createPromHttpHandler(metricsCtx, allFetchers)
+	snapshots, err := createSnapshots(cfg, allFetchers)
 	if err != nil {
-		zap.L().Error("Failed to create prometheus handler", zap.Error(err))
+		zap.L().Error("Failed to create fetchers", zap.Error(err))
+		os.Exit(1)
+	}
+	var extraCollectors []prometheus.Collector
+	if *cfg.MetricsSelf {
+		extraCollectors = createSelfCollectors(metricsCtx, allFetchers)
+	}
+	registry, collector, err := createRegistry(metricsCtx, snapshots, extraCollectors...)
+	if err != nil {
+		zap.L().Error("Failed to create prometheus registry", zap.Error(err))
+		os.Exit(1)
+	}
+	groupCollectors, err := createGroupCollectors(cfg, metricsCtx, snapshots, collector, extraCollectors)
+	if err != nil {
+		zap.L().Error("Failed to create collectors", zap.Error(err))
+		os.Exit(1)
+	}
+	for _, c := range groupCollectors {
+		registry.MustRegister(c)
+	}
+	peersCtx := cfg.CreatePeersContext()
+	var gatherer prometheus.Gatherer = registry
+	var scraper *peers.Scraper
+	if peersCtx.ProbeEnabled || peersCtx.Aggregate() {
+		scraper, err = peers.NewScraper(metricsCtx.Namespace, peersCtx)
+		if err != nil {
+			zap.L().Error("Failed to create peers scraper", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+	var peerLister api.PeerLister
+	if peersCtx.Aggregate() {
+		aggregator := peers.NewAggregator(peersCtx, scraper, registry)
+		gatherer = aggregator
+		peerLister = aggregator
+	}
+	serviceDiscovery, err := api.NewServiceDiscovery(*cfg.ListenAddress, snapshots, peerLister)
+	if err != nil {
+		zap.L().Error("Failed to create service discovery", zap.Error(err))
+		os.Exit(1)
+	}
+	handler := createPromHttpHandler(gatherer, collector, extraCollectors, groupCollectors)
+	health, err := api.NewHealth(cfg.CreateReadinessContext(), snapshots)
+	if err != nil {
+		zap.L().Error("Failed to create health endpoints", zap.Error(err))
 		os.Exit(1)
 	}
 
@@ -64,13 +338,66 @@ func main() {
 		"version", ProgramVersion,
 		"listen_address", *cfg.ListenAddress,
 		"telemetry_path", *cfg.TelemetryPath,
+		"collector_interval", cfg.CollectorInterval.String(),
 		"pid", os.Getpid(),
 	)
 
-	http.Handle(*cfg.TelemetryPath, handler)
-	err = http.ListenAndServe(*cfg.ListenAddress, nil)
+	if *cfg.CollectorInterval > 0 {
+		go snapshots.Run(ctx, *cfg.CollectorInterval)
+	}
+	sinksWg, err := startSinks(ctx, cfg, metricsCtx, registry, collector)
 	if err != nil {
+		zap.L().Error("Failed to start metrics sinks", zap.Error(err))
+		os.Exit(1)
+	}
+	if *cfg.AlertsRulesFile != "" {
+		if err := startAlerts(ctx, cfg.CreateAlertsContext(), metricsCtx, registry); err != nil {
+			zap.L().Error("Failed to start alert rules", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	http.Handle(*cfg.TelemetryPath, handler)
+	http.Handle(api.Prefix, api.NewAPI(snapshots))
+	http.HandleFunc("/healthz", health.Healthz)
+	http.HandleFunc("/readyz", health.Readyz)
+	http.Handle("/sd", serviceDiscovery)
+	if *cfg.SDFile != "" {
+		go serviceDiscovery.RunFileWriter(ctx, *cfg.SDFile, *cfg.SDFileInterval)
+	}
+	if peersCtx.ProbeEnabled {
+		http.Handle("/probe", peers.NewProbeHandler(scraper))
+	}
+	if *cfg.EventsInterval > 0 {
+		broker := events.NewBroker()
+		if len(*cfg.NotifyWebhookURLs) > 0 {
+			notifier, err := events.NewNotifier(cfg.CreateNotifyContext(), collector.InstanceSpec())
+			if err != nil {
+				zap.L().Error("Failed to create notifier", zap.Error(err))
+				os.Exit(1)
+			}
+			notifications, _ := broker.Subscribe()
+			go notifier.Run(ctx, notifications)
+		}
+		watcher := events.NewWatcher(cfg.CreateEventsContext(), snapshots, broker, collector.InstanceLabels())
+		go watcher.Run(ctx)
+		http.Handle(api.Prefix+"events", events.NewSSEHandler(broker))
+	} else if len(*cfg.NotifyWebhookURLs) > 0 {
+		zap.L().Error("Notifications require the events, set --events.interval")
+		os.Exit(1)
+	}
+	server := &http.Server{Addr: *cfg.ListenAddress}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		zap.L().Error("Failed to start http server", zap.Error(err))
 		os.Exit(1)
 	}
+	sinksWg.Wait()
+	zap.L().Info("Application stopped")
 }
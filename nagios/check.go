@@ -0,0 +1,229 @@
+package nagios
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// State is the status of a Nagios plugin, its value is the exit code
+type State int
+
+const (
+	OK State = iota
+	Warning
+	Critical
+	Unknown
+)
+
+// Monit states of a healthy process
+const (
+	runningStatus   = "running"
+	monitoredStatus = "monitored"
+)
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// severity orders the states, a CRITICAL result is not hidden by an UNKNOWN one
+func (s State) severity() int {
+	switch s {
+	case OK:
+		return 0
+	case Warning:
+		return 1
+	case Unknown:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Perfdata is a performance data item, the thresholds are Nagios ranges
+type Perfdata struct {
+	Label    string
+	Value    float64
+	Unit     string // empty, s, % or B
+	Warning  string
+	Critical string
+	Min      string
+	Max      string
+}
+
+func (p Perfdata) String() string {
+	label := p.Label
+	if strings.ContainsAny(label, " '=") {
+		label = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	value := strconv.FormatFloat(p.Value, 'f', -1, 64)
+	return strings.TrimRight(fmt.Sprintf("%s=%s%s;%s;%s;%s;%s", label, value, p.Unit, p.Warning, p.Critical, p.Min, p.Max), ";")
+}
+
+// Result is the outcome of a check
+type Result struct {
+	State    State
+	Problems []string // messages of the checks which are not OK
+	Summary  []string // messages describing the checked state
+	Perfdata []Perfdata
+}
+
+// raise records a problem, the state of the result is the most severe one
+func (r *Result) raise(state State, format string, args ...any) {
+	if state.severity() > r.State.severity() {
+		r.State = state
+	}
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// String returns the plugin output line: BOSHI <STATE> - <message> | <perfdata>
+func (r *Result) String() string {
+	message := strings.Join(r.Summary, ", ")
+	if len(r.Problems) > 0 {
+		message = strings.Join(r.Problems, "; ")
+	}
+	line := fmt.Sprintf("BOSHI %s - %s", r.State, message)
+	if len(r.Perfdata) > 0 {
+		perfdata := make([]string, 0, len(r.Perfdata))
+		for _, p := range r.Perfdata {
+			perfdata = append(perfdata, p.String())
+		}
+		line += " | " + strings.Join(perfdata, " ")
+	}
+	return line
+}
+
+// Evaluate checks the Monit processes, the disks, the memory and the certificate expiries against the thresholds
+func Evaluate(checkContext *config.CheckContext, monit fetchers.Snapshot[fetchers.MonitStat], system fetchers.Snapshot[fetchers.SystemStat], certExpiries map[string]time.Time, now time.Time) *Result {
+	result := &Result{State: OK}
+	evaluateProcesses(checkContext, monit, result)
+	evaluateSystem(checkContext, system, result)
+	evaluateCertificates(checkContext, certExpiries, now, result)
+	return result
+}
+
+func evaluateProcesses(checkContext *config.CheckContext, monit fetchers.Snapshot[fetchers.MonitStat], result *Result) {
+	if monit.Value == nil {
+		result.raise(Unknown, "monit status is not available: %v", monit.Err)
+		return
+	}
+	names := make([]string, 0, len(monit.Value.Processes))
+	for name := range monit.Value.Processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var failing []string
+	for _, name := range names {
+		process := monit.Value.Processes[name]
+		if process.Status != runningStatus || process.MonitoringStatus != monitoredStatus {
+			failing = append(failing, fmt.Sprintf("%s (%s, %s)", name, process.Status, process.MonitoringStatus))
+		}
+	}
+	count := len(failing)
+	switch {
+	case checkContext.FailingProcessesCritical > 0 && count >= checkContext.FailingProcessesCritical:
+		result.raise(Critical, "%d failing processes: %s", count, strings.Join(failing, ", "))
+	case checkContext.FailingProcessesWarning > 0 && count >= checkContext.FailingProcessesWarning:
+		result.raise(Warning, "%d failing processes: %s", count, strings.Join(failing, ", "))
+	}
+	result.Summary = append(result.Summary, fmt.Sprintf("%d/%d processes running", len(names)-count, len(names)))
+	result.Perfdata = append(result.Perfdata, Perfdata{
+		Label: "failing_processes", Value: float64(count),
+		Warning: countThreshold(checkContext.FailingProcessesWarning), Critical: countThreshold(checkContext.FailingProcessesCritical),
+		Min: "0", Max: strconv.Itoa(len(names)),
+	})
+}
+
+func evaluateSystem(checkContext *config.CheckContext, system fetchers.Snapshot[fetchers.SystemStat], result *Result) {
+	if system.Value == nil {
+		result.raise(Unknown, "system stat is not available: %v", system.Err)
+		return
+	}
+	if disks := system.Value.Disks; disks != nil {
+		for _, d := range []struct {
+			name  string
+			usage *disk.UsageStat
+		}{{"root", disks.RootDisk}, {"data", disks.DataDisk}, {"store", disks.StoreDisk}} {
+			if d.usage != nil {
+				evaluateRatio(d.name+" disk", d.name+"_disk", d.usage.UsedPercent, checkContext.DiskWarning, checkContext.DiskCritical, result)
+			}
+		}
+	}
+	if memory := system.Value.Memory; memory != nil && memory.VM != nil {
+		evaluateRatio("memory", "memory", memory.VM.UsedPercent, checkContext.MemoryWarning, checkContext.MemoryCritical, result)
+	}
+}
+
+// evaluateRatio checks a used percentage against warning and critical ratios
+func evaluateRatio(name, label string, usedPercent, warning, critical float64, result *Result) {
+	switch {
+	case usedPercent >= critical*100:
+		result.raise(Critical, "%s %.1f%% used", name, usedPercent)
+	case usedPercent >= warning*100:
+		result.raise(Warning, "%s %.1f%% used", name, usedPercent)
+	}
+	result.Perfdata = append(result.Perfdata, Perfdata{
+		Label: label, Value: roundTo(usedPercent, 2), Unit: "%",
+		Warning: formatFloat(roundTo(warning*100, 2)), Critical: formatFloat(roundTo(critical*100, 2)), Min: "0", Max: "100",
+	})
+}
+
+func evaluateCertificates(checkContext *config.CheckContext, certExpiries map[string]time.Time, now time.Time, result *Result) {
+	names := make([]string, 0, len(certExpiries))
+	for name := range certExpiries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		remaining := certExpiries[name].Sub(now)
+		switch {
+		case remaining <= checkContext.CertExpiryCritical:
+			result.raise(Critical, "certificate of %s expires in %s", name, formatDays(remaining))
+		case remaining <= checkContext.CertExpiryWarning:
+			result.raise(Warning, "certificate of %s expires in %s", name, formatDays(remaining))
+		}
+		// an alert is raised when the remaining validity drops below the threshold
+		result.Perfdata = append(result.Perfdata, Perfdata{
+			Label: "cert_expiry_" + name, Value: roundTo(remaining.Seconds(), 0), Unit: "s",
+			Warning:  formatFloat(checkContext.CertExpiryWarning.Seconds()) + ":",
+			Critical: formatFloat(checkContext.CertExpiryCritical.Seconds()) + ":",
+		})
+	}
+}
+
+// countThreshold returns the Nagios range of a count threshold raising at count, empty if disabled
+func countThreshold(count int) string {
+	if count <= 0 {
+		return ""
+	}
+	return strconv.Itoa(count - 1)
+}
+
+func formatDays(d time.Duration) string {
+	return fmt.Sprintf("%.1f days", d.Hours()/24)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func roundTo(f float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(f*scale) / scale
+}
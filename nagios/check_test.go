@@ -0,0 +1,100 @@
+package nagios
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCheckContext = &config.CheckContext{
+	FailingProcessesWarning:  0,
+	FailingProcessesCritical: 1,
+	DiskWarning:              0.8,
+	DiskCritical:             0.9,
+	MemoryWarning:            0.9,
+	MemoryCritical:           0.95,
+	CertExpiryWarning:        30 * 24 * time.Hour,
+	CertExpiryCritical:       7 * 24 * time.Hour,
+}
+
+func monitSnapshot(processes map[string]fetchers.MonitProcessStatus) fetchers.Snapshot[fetchers.MonitStat] {
+	return fetchers.Snapshot[fetchers.MonitStat]{Value: &fetchers.MonitStat{Processes: processes}}
+}
+
+func systemSnapshot(dataDiskPercent, memoryPercent float64) fetchers.Snapshot[fetchers.SystemStat] {
+	return fetchers.Snapshot[fetchers.SystemStat]{Value: &fetchers.SystemStat{
+		Disks:  &fetchers.DisksStat{RootDisk: &disk.UsageStat{UsedPercent: 41.5}, DataDisk: &disk.UsageStat{UsedPercent: dataDiskPercent}},
+		Memory: &fetchers.MemoryStat{VM: &mem.VirtualMemoryStat{UsedPercent: memoryPercent}},
+	}}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 5, 23, 11, 0, 0, 0, time.UTC)
+	running := fetchers.MonitProcessStatus{Status: "running", MonitoringStatus: "monitored"}
+
+	tests := []struct {
+		name         string
+		monit        fetchers.Snapshot[fetchers.MonitStat]
+		system       fetchers.Snapshot[fetchers.SystemStat]
+		certExpiries map[string]time.Time
+		expected     string
+		state        State
+	}{
+		{
+			name:         "ok",
+			monit:        monitSnapshot(map[string]fetchers.MonitProcessStatus{"nats": running, "uaa": running}),
+			system:       systemSnapshot(50, 30),
+			certExpiries: map[string]time.Time{"uaa": now.Add(60 * 24 * time.Hour)},
+			expected: "BOSHI OK - 2/2 processes running | failing_processes=0;;0;0;2 root_disk=41.5%;80;90;0;100 " +
+				"data_disk=50%;80;90;0;100 memory=30%;90;95;0;100 cert_expiry_uaa=5184000s;2592000:;604800:",
+			state: OK,
+		},
+		{
+			name:   "warning",
+			monit:  monitSnapshot(map[string]fetchers.MonitProcessStatus{"nats": running}),
+			system: systemSnapshot(85.25, 30),
+			expected: "BOSHI WARNING - data disk 85.2% used | failing_processes=0;;0;0;1 root_disk=41.5%;80;90;0;100 " +
+				"data_disk=85.25%;80;90;0;100 memory=30%;90;95;0;100",
+			state: Warning,
+		},
+		{
+			name: "critical",
+			monit: monitSnapshot(map[string]fetchers.MonitProcessStatus{
+				"nats": running,
+				"uaa":  {Status: "Does not exist", MonitoringStatus: "monitored"},
+			}),
+			system:       systemSnapshot(50, 91),
+			certExpiries: map[string]time.Time{"uaa": now.Add(24 * time.Hour)},
+			expected: "BOSHI CRITICAL - 1 failing processes: uaa (Does not exist, monitored); memory 91.0% used; certificate of uaa expires in 1.0 days | " +
+				"failing_processes=1;;0;0;2 root_disk=41.5%;80;90;0;100 data_disk=50%;80;90;0;100 memory=91%;90;95;0;100 cert_expiry_uaa=86400s;2592000:;604800:",
+			state: Critical,
+		},
+		{
+			name:     "unknown",
+			monit:    fetchers.Snapshot[fetchers.MonitStat]{Err: errors.New("connection refused")},
+			system:   systemSnapshot(50, 30),
+			expected: "BOSHI UNKNOWN - monit status is not available: connection refused | root_disk=41.5%;80;90;0;100 data_disk=50%;80;90;0;100 memory=30%;90;95;0;100",
+			state:    Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(testCheckContext, tt.monit, tt.system, tt.certExpiries, now)
+			assert.Equal(t, tt.expected, result.String())
+			assert.Equal(t, tt.state, result.State)
+		})
+	}
+}
+
+func TestResult_CriticalOverUnknown(t *testing.T) {
+	result := &Result{}
+	result.raise(Critical, "data disk full")
+	result.raise(Unknown, "monit unavailable")
+	assert.Equal(t, Critical, result.State)
+}
@@ -0,0 +1,131 @@
+package peers
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Aggregator gathers the local metrics together with the metrics of all peers, the peers are
+// scraped in parallel on every Gather
+type Aggregator struct {
+	peersContext *config.PeersContext
+	scraper      *Scraper
+	local        prometheus.Gatherer
+}
+
+var _ prometheus.Gatherer = (*Aggregator)(nil)
+
+// targetGroup is an entry of a Prometheus file_sd file, the labels are ignored
+type targetGroup struct {
+	Targets []string `yaml:"targets"`
+}
+
+func NewAggregator(peersContext *config.PeersContext, scraper *Scraper, local prometheus.Gatherer) *Aggregator {
+	return &Aggregator{peersContext: peersContext, scraper: scraper, local: local}
+}
+
+// Targets returns the sorted peers of the static list and the peers file
+func (a *Aggregator) Targets() ([]string, error) {
+	targets := slices.Clone(a.peersContext.Targets)
+	if a.peersContext.File != "" {
+		data, err := os.ReadFile(a.peersContext.File)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read peers file '%s', error: %v", a.peersContext.File, err)
+		}
+		var groups []targetGroup
+		if err := yaml.Unmarshal(data, &groups); err != nil {
+			return nil, fmt.Errorf("cannot parse peers file '%s', error: %v", a.peersContext.File, err)
+		}
+		for _, group := range groups {
+			targets = append(targets, group.Targets...)
+		}
+	}
+	sort.Strings(targets)
+	return slices.Compact(targets), nil
+}
+
+// Gather returns the local metrics, the metrics of the reachable peers and the status of all peers.
+// The local metrics keep the instance assigned by Prometheus, the peer metrics carry their own instance label.
+func (a *Aggregator) Gather() ([]*dto.MetricFamily, error) {
+	localFamilies, localErr := a.local.Gather()
+	targets, err := a.Targets()
+	if err != nil {
+		// serve the static peers, the file is likely being rewritten
+		zap.L().Warn("Failed to read peers, only the static peers are scraped", zap.Error(err))
+		targets = slices.Clone(a.peersContext.Targets)
+	}
+
+	results := make([]scrapeResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = a.scraper.scrape(context.Background(), target)
+			if results[i].err != nil {
+				zap.L().Warn("Failed to scrape peer", zap.String("target", target), zap.Error(results[i].err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	status, err := a.scraper.statusFamilies(results)
+	if err != nil {
+		return nil, err
+	}
+	families := mergeFamilies(localFamilies, status)
+	for _, result := range results {
+		families = mergeFamilies(families, result.families)
+	}
+	return families, localErr
+}
+
+// mergeFamilies adds the metrics of the other families to the families of the same name, the help of the first
+// family is kept and families whose type differs from the first one are dropped
+func mergeFamilies(families []*dto.MetricFamily, others []*dto.MetricFamily) []*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	for _, other := range others {
+		family, ok := byName[other.GetName()]
+		if !ok {
+			family = &dto.MetricFamily{Name: other.Name, Help: other.Help, Type: other.Type}
+			byName[other.GetName()] = family
+			families = append(families, family)
+		}
+		if family.GetType() != other.GetType() {
+			zap.L().Debug("Dropping peer metric family with conflicting type", zap.String("family", other.GetName()))
+			continue
+		}
+		family.Metric = append(family.Metric, other.Metric...)
+	}
+	for _, family := range families {
+		sort.Slice(family.Metric, func(i, j int) bool { return lessLabels(family.Metric[i], family.Metric[j]) })
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families
+}
+
+// lessLabels orders metrics by their number of labels and label values like the Prometheus registry
+func lessLabels(a, b *dto.Metric) bool {
+	if len(a.GetLabel()) != len(b.GetLabel()) {
+		return len(a.GetLabel()) < len(b.GetLabel())
+	}
+	for i := range a.GetLabel() {
+		if a.Label[i].GetValue() != b.Label[i].GetValue() {
+			return a.Label[i].GetValue() < b.Label[i].GetValue()
+		}
+	}
+	return false
+}
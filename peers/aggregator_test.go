@@ -0,0 +1,54 @@
+package peers
+
+import (
+	"boshi_exporter/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator(t *testing.T) {
+	_, target0 := newTestPeer(t, 0)
+	_, target1 := newTestPeer(t, 1)
+	peersFile := filepath.Join(t.TempDir(), "peers.json")
+	require.NoError(t, os.WriteFile(peersFile, []byte(fmt.Sprintf(`[{"targets": ["%s", "127.0.0.1:1"]}]`, target1)), 0o644))
+
+	local := prometheus.NewRegistry()
+	localUp := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "boshi_monit_process_up", Help: "Whether the process is running"}, []string{"bosh_instance_index", "process_name"})
+	localUp.WithLabelValues("2", "nats").Set(1)
+	local.MustRegister(localUp)
+
+	peersContext := &config.PeersContext{
+		Targets: []string{target0, target1}, File: peersFile, Scheme: "http", MetricsPath: "/metrics", Timeout: time.Second,
+	}
+	scraper, err := NewScraper("boshi", peersContext)
+	require.NoError(t, err)
+	aggregator := NewAggregator(peersContext, scraper, local)
+
+	targets, err := aggregator.Targets()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{target0, target1, "127.0.0.1:1"}, targets)
+
+	expected := fmt.Sprintf(`
+# HELP boshi_monit_process_up Whether the process is running
+# TYPE boshi_monit_process_up gauge
+boshi_monit_process_up{bosh_instance_index="2",process_name="nats"} 1
+boshi_monit_process_up{bosh_instance_index="0",instance="%[1]s",process_name="nats"} 1
+boshi_monit_process_up{bosh_instance_index="0",instance="%[1]s",process_name="route_registrar"} 0
+boshi_monit_process_up{bosh_instance_index="1",instance="%[2]s",process_name="nats"} 1
+boshi_monit_process_up{bosh_instance_index="1",instance="%[2]s",process_name="route_registrar"} 0
+# HELP boshi_peer_up Whether the last scrape of the peer succeeded (1=success)
+# TYPE boshi_peer_up gauge
+boshi_peer_up{instance="127.0.0.1:1"} 0
+boshi_peer_up{instance="%[1]s"} 1
+boshi_peer_up{instance="%[2]s"} 1
+`, target0, target1)
+	require.NoError(t, testutil.GatherAndCompare(aggregator, strings.NewReader(expected), "boshi_monit_process_up", "boshi_peer_up"))
+}
@@ -0,0 +1,34 @@
+package peers
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+// NewProbeHandler serves /probe?target=<host:port> with the metrics of the target peer and its
+// <namespace>_peer_up status, a failed scrape is reported by the status with HTTP 200
+func NewProbeHandler(scraper *Scraper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		result := scraper.scrape(r.Context(), target)
+		if result.err != nil {
+			zap.L().Warn("Failed to probe peer", zap.String("target", target), zap.Error(result.err))
+		}
+		gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			status, err := scraper.statusFamilies([]scrapeResult{result})
+			if err != nil {
+				return nil, err
+			}
+			return mergeFamilies(result.families, status), nil
+		})
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
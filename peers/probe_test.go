@@ -0,0 +1,67 @@
+package peers
+
+import (
+	"boshi_exporter/config"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const peerMetrics = `# HELP boshi_monit_process_up Whether the process is running
+# TYPE boshi_monit_process_up gauge
+boshi_monit_process_up{bosh_instance_index="%[1]d",process_name="nats"} 1
+boshi_monit_process_up{bosh_instance_index="%[1]d",process_name="route_registrar"} 0
+`
+
+// newTestPeer starts a peer exporter serving peerMetrics for the given instance index
+func newTestPeer(t *testing.T, index int) (*httptest.Server, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, peerMetrics, index)
+	}))
+	t.Cleanup(server.Close)
+	return server, strings.TrimPrefix(server.URL, "http://")
+}
+
+func newTestScraper(t *testing.T, targets ...string) *Scraper {
+	scraper, err := NewScraper("boshi", &config.PeersContext{
+		Targets: targets, Scheme: "http", MetricsPath: "/metrics", Timeout: time.Second,
+	})
+	require.NoError(t, err)
+	return scraper
+}
+
+func probe(t *testing.T, handler http.Handler, query string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/probe"+query, nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	return recorder.Code, string(body)
+}
+
+func TestProbeHandler(t *testing.T) {
+	_, target := newTestPeer(t, 0)
+	handler := NewProbeHandler(newTestScraper(t))
+
+	code, body := probe(t, handler, "?target="+target)
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, fmt.Sprintf(`boshi_monit_process_up{bosh_instance_index="0",instance="%s",process_name="nats"} 1`, target))
+	require.Contains(t, body, fmt.Sprintf(`boshi_peer_up{instance="%s"} 1`, target))
+
+	code, body = probe(t, handler, "?target=127.0.0.1:1")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `boshi_peer_up{instance="127.0.0.1:1"} 0`)
+	require.NotContains(t, body, "boshi_monit_process_up")
+
+	code, _ = probe(t, handler, "")
+	require.Equal(t, http.StatusBadRequest, code)
+}
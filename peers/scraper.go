@@ -0,0 +1,124 @@
+package peers
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+const instanceLabel = "instance"
+
+// Scraper fetches the metrics of peer boshi_exporters
+type Scraper struct {
+	peersContext *config.PeersContext
+	namespace    string
+	client       *http.Client
+}
+
+// scrapeResult is the outcome of scraping a single peer
+type scrapeResult struct {
+	target   string
+	families []*dto.MetricFamily
+	duration time.Duration
+	err      error
+}
+
+func NewScraper(namespace string, peersContext *config.PeersContext) (*Scraper, error) {
+	client, err := config.NewHTTPClient(peersContext.TLS, peersContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Scraper{
+		peersContext: peersContext,
+		namespace:    namespace,
+		client:       client,
+	}, nil
+}
+
+// Scrape fetches the metrics of the target given as host:port, the instance label of all metrics is set to the target
+func (s *Scraper) Scrape(ctx context.Context, target string) ([]*dto.MetricFamily, error) {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return nil, fmt.Errorf("invalid target '%s', expected host:port", target)
+	}
+	targetURL := url.URL{Scheme: s.peersContext.Scheme, Host: target, Path: s.peersContext.MetricsPath}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot scrape peer '%s', error: %v", target, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot scrape peer '%s', HTTP status %s", target, resp.Status)
+	}
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse metrics of peer '%s', error: %v", target, err)
+	}
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, family := range parsed {
+		for _, metric := range family.GetMetric() {
+			setInstance(metric, target)
+		}
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, nil
+}
+
+// scrape fetches the metrics of the target and measures the scrape
+func (s *Scraper) scrape(ctx context.Context, target string) scrapeResult {
+	start := time.Now()
+	families, err := s.Scrape(ctx, target)
+	return scrapeResult{target: target, families: families, duration: time.Since(start), err: err}
+}
+
+// statusFamilies returns the <namespace>_peer_up and <namespace>_peer_scrape_duration_seconds
+// families of the scraped peers
+func (s *Scraper) statusFamilies(results []scrapeResult) ([]*dto.MetricFamily, error) {
+	up := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: s.namespace, Subsystem: "peer", Name: "up",
+		Help: "Whether the last scrape of the peer succeeded (1=success)",
+	}, []string{instanceLabel})
+	duration := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: s.namespace, Subsystem: "peer", Name: "scrape_duration_seconds",
+		Help: "Duration of the last scrape of the peer (seconds)",
+	}, []string{instanceLabel})
+	for _, result := range results {
+		value := 1.0
+		if result.err != nil {
+			value = 0
+		}
+		up.WithLabelValues(result.target).Set(value)
+		duration.WithLabelValues(result.target).Set(result.duration.Seconds())
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(up, duration)
+	return registry.Gather()
+}
+
+// setInstance sets the instance label of the metric, the labels are kept sorted by name
+func setInstance(metric *dto.Metric, target string) {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == instanceLabel {
+			pair.Value = proto.String(target)
+			return
+		}
+	}
+	metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(instanceLabel), Value: proto.String(target)})
+	sort.Slice(metric.Label, func(i, j int) bool { return metric.Label[i].GetName() < metric.Label[j].GetName() })
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"boshi_exporter/collectors"
+	"boshi_exporter/config"
+	"boshi_exporter/simulator"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runSimulate serves the metrics of the simulated instances through the regular collectors until it is interrupted,
+// every instance listens on its own port counted up from the port of the listen address
+func runSimulate(cfg *config.Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	host, portText, err := net.SplitHostPort(*cfg.ListenAddress)
+	if err != nil {
+		zap.L().Error("Failed to parse listen address", zap.Error(err))
+		return 1
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		zap.L().Error("Failed to parse listen address port", zap.String("port", portText), zap.Error(err))
+		return 1
+	}
+	simulateCtx := cfg.CreateSimulateContext()
+	instances, err := simulator.NewInstances(simulateCtx, time.Now())
+	if err != nil {
+		zap.L().Error("Failed to create simulated instances", zap.Error(err))
+		return 1
+	}
+	metricsCtx, err := cfg.CreateMetricsContext(collectors.LabelNames)
+	if err != nil {
+		zap.L().Error("Invalid metrics configuration", zap.Error(err))
+		return 1
+	}
+	servers := make([]*http.Server, 0, len(instances))
+	for i, instance := range instances {
+		registry, collector, err := createRegistry(metricsCtx, instance.Snapshots())
+		if err != nil {
+			zap.L().Error("Failed to create prometheus registry", zap.Error(err))
+			return 1
+		}
+		mux := http.NewServeMux()
+		mux.Handle(*cfg.TelemetryPath, createPromHttpHandler(registry, collector, nil, nil))
+		address := net.JoinHostPort(host, strconv.Itoa(port+i))
+		servers = append(servers, &http.Server{Addr: address, Handler: mux})
+		spec := collector.InstanceSpec()
+		zap.S().Infow("Simulating instance",
+			"deployment", spec.Deployment,
+			"instance", spec.Name+"/"+strconv.Itoa(spec.Index),
+			"listen_address", address,
+		)
+	}
+
+	zap.S().Infow("Starting simulation",
+		"program", ProgramName,
+		"version", ProgramVersion,
+		"scenario", simulateCtx.Scenario,
+		"period", simulateCtx.Period.String(),
+		"instances", simulateCtx.Instances,
+	)
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}()
+	}
+	code := 0
+	select {
+	case <-ctx.Done():
+	case err := <-failed:
+		zap.L().Error("Failed to start http server", zap.Error(err))
+		code = 1
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		_ = server.Shutdown(shutdownCtx)
+	}
+	zap.L().Info("Simulation stopped")
+	return code
+}
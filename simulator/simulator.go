@@ -0,0 +1,287 @@
+package simulator
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// Resources of a simulated instance
+const (
+	gib = 1 << 30
+	mib = 1 << 20
+
+	memoryTotal    = 8 * gib
+	swapTotal      = 2 * gib
+	rootDiskTotal  = 3 * gib
+	dataDiskTotal  = 20 * gib
+	storeDiskTotal = 50 * gib
+	logicalCores   = 4
+	physicalCores  = 2
+
+	instanceName  = "simulated"
+	monitVersion  = "5.2.5"
+	crashingName  = "app" // process restarted by the crashloop scenario
+	runningStatus = "running"
+)
+
+// processNames are the Monit processes of every simulated instance
+var processNames = []string{"app", "worker", "bosh-dns"}
+
+// Instance generates the spec, Monit status and system metrics of a synthetic Bosh instance,
+// the values are derived from the time elapsed since the start of the simulation
+type Instance struct {
+	spec     fetchers.InstanceSpec
+	scenario string // empty for a healthy instance
+	period   time.Duration
+	start    time.Time
+	now      func() time.Time
+}
+
+// state is the simulated condition of an instance at a point in time
+type state struct {
+	memoryUsed    float64 // ratios of the resources used
+	swapUsed      float64
+	rootDiskUsed  float64
+	dataDiskUsed  float64
+	storeDiskUsed float64
+
+	load1     float64
+	load5     float64
+	load15    float64
+	cpuUser   float64 // percent
+	cpuSystem float64
+	cpuIOWait float64
+
+	crashed   bool          // whether the crashing process is down
+	restarts  int           // number of restarts of the crashing process
+	appUptime time.Duration // uptime of the crashing process
+	appMemory uint64        // memory of the crashing process in bytes
+}
+
+// NewInstances creates the simulated instances, the scenario is played on the first instance only
+// so the others serve as a healthy baseline
+func NewInstances(simulateContext *config.SimulateContext, start time.Time) ([]*Instance, error) {
+	if simulateContext.Instances < 1 {
+		return nil, fmt.Errorf("cannot simulate '%d' instances, at least one is required", simulateContext.Instances)
+	}
+	if simulateContext.Period <= 0 {
+		return nil, fmt.Errorf("cannot simulate with period '%s', it must be positive", simulateContext.Period)
+	}
+	switch simulateContext.Scenario {
+	case config.ScenarioCrashloop, config.ScenarioDiskFill, config.ScenarioSwapStorm:
+	default:
+		return nil, fmt.Errorf("unknown scenario '%s'", simulateContext.Scenario)
+	}
+	instances := make([]*Instance, simulateContext.Instances)
+	for index := range instances {
+		scenario := ""
+		if index == 0 {
+			scenario = simulateContext.Scenario
+		}
+		instances[index] = &Instance{
+			spec:     newInstanceSpec(simulateContext.Deployment, index),
+			scenario: scenario,
+			period:   simulateContext.Period,
+			start:    start,
+			now:      time.Now,
+		}
+	}
+	return instances, nil
+}
+
+func newInstanceSpec(deployment string, index int) fetchers.InstanceSpec {
+	host := 10 + index
+	return fetchers.InstanceSpec{
+		Deployment: deployment,
+		Name:       instanceName,
+		Index:      index,
+		ID:         fmt.Sprintf("00000000-0000-4000-8000-%012d", index),
+		AZ:         fmt.Sprintf("z%d", index%3+1),
+		Networks: map[string]fetchers.NetworkSpec{
+			"default": {IP: fmt.Sprintf("10.0.%d.%d", host/256, host%256), Default: []string{"dns", "gateway"}},
+		},
+	}
+}
+
+// Spec returns the instance spec
+func (i *Instance) Spec(_ context.Context) (*fetchers.InstanceSpec, error) {
+	spec := i.spec
+	return &spec, nil
+}
+
+// Snapshots creates the snapshot sources serving the simulated values in place of the fetchers
+func (i *Instance) Snapshots() *fetchers.Snapshots {
+	return &fetchers.Snapshots{
+		Spec:   fetchers.NewSnapshotSource("spec", i.Spec),
+		Monit:  fetchers.NewSnapshotSource("monit", i.Monit),
+		System: fetchers.NewSnapshotSource("system", i.System),
+	}
+}
+
+// Monit returns the simulated `monit status` of the instance
+func (i *Instance) Monit(_ context.Context) (*fetchers.MonitStat, error) {
+	now := i.now()
+	elapsed := now.Sub(i.start)
+	s := i.stateAt(elapsed)
+	collected := now.Truncate(time.Second)
+	stat := &fetchers.MonitStat{
+		Version:   monitVersion,
+		Uptime:    (elapsed + time.Hour).Truncate(time.Minute),
+		Processes: make(map[string]fetchers.MonitProcessStatus, len(processNames)),
+		System: fetchers.MonitSystemStatus{
+			Status:            runningStatus,
+			MonitoringStatus:  "monitored",
+			LoadAvg1:          s.load1,
+			LoadAvg5:          s.load5,
+			LoadAvg15:         s.load15,
+			CPUUserPercent:    s.cpuUser,
+			CPUSystemPercent:  s.cpuSystem,
+			CPUIOWaitPercent:  s.cpuIOWait,
+			MemoryUsedBytes:   uint64(s.memoryUsed * memoryTotal),
+			MemoryUsedPercent: s.memoryUsed * 100,
+			SwapUsedBytes:     uint64(s.swapUsed * swapTotal),
+			SwapUsedPercent:   s.swapUsed * 100,
+			DataCollected:     collected,
+		},
+	}
+	for n, name := range processNames {
+		process := fetchers.MonitProcessStatus{
+			Status:               runningStatus,
+			MonitoringStatus:     "monitored",
+			PID:                  fmt.Sprintf("%d", 1000+100*i.spec.Index+n),
+			ParentPID:            "1",
+			Uptime:               (elapsed + time.Hour).Truncate(time.Minute),
+			MemoryUsedBytes:      uint64(200+50*n) * mib,
+			MemoryUsedBytesTotal: uint64(200+50*n) * mib,
+			CPUUsedPercent:       2 + wave(elapsed, i.spec.Index+n),
+			DataCollected:        collected,
+		}
+		if name == crashingName && i.scenario == config.ScenarioCrashloop {
+			process.PID = fmt.Sprintf("%d", 1000+100*i.spec.Index+n+10*s.restarts)
+			process.Uptime = s.appUptime.Truncate(time.Minute)
+			process.MemoryUsedBytes = s.appMemory
+			process.MemoryUsedBytesTotal = s.appMemory
+			if s.crashed {
+				process.Status = "Does not exist"
+				process.PID = "-"
+				process.CPUUsedPercent = 0
+			}
+		}
+		process.CPUUsedPercentTotal = process.CPUUsedPercent
+		process.MemoryUsedPercent = roundTo(float64(process.MemoryUsedBytes)/memoryTotal*100, 1)
+		process.MemoryUsedPercentTotal = process.MemoryUsedPercent
+		stat.Processes[name] = process
+	}
+	return stat, nil
+}
+
+// System returns the simulated system metrics of the instance
+func (i *Instance) System(_ context.Context) (*fetchers.SystemStat, error) {
+	s := i.stateAt(i.now().Sub(i.start))
+	return &fetchers.SystemStat{
+		Host: &fetchers.HostStat{Load: &load.AvgStat{Load1: s.load1, Load5: s.load5, Load15: s.load15}},
+		CPU:  &fetchers.CPUStat{LogicalCores: logicalCores, PhysicalCores: physicalCores},
+		Memory: &fetchers.MemoryStat{
+			VM: &mem.VirtualMemoryStat{
+				Total:       memoryTotal,
+				Used:        uint64(s.memoryUsed * memoryTotal),
+				Available:   memoryTotal - uint64(s.memoryUsed*memoryTotal),
+				Free:        memoryTotal - uint64(s.memoryUsed*memoryTotal),
+				UsedPercent: s.memoryUsed * 100,
+			},
+			SwapMemory: &mem.SwapMemoryStat{
+				Total:       swapTotal,
+				Used:        uint64(s.swapUsed * swapTotal),
+				Free:        swapTotal - uint64(s.swapUsed*swapTotal),
+				UsedPercent: s.swapUsed * 100,
+			},
+		},
+		Disks: &fetchers.DisksStat{
+			RootDisk:  diskUsage("/", rootDiskTotal, s.rootDiskUsed),
+			DataDisk:  diskUsage("/var/vcap/data", dataDiskTotal, s.dataDiskUsed),
+			StoreDisk: diskUsage("/var/vcap/store", storeDiskTotal, s.storeDiskUsed),
+		},
+	}, nil
+}
+
+func diskUsage(path string, total uint64, used float64) *disk.UsageStat {
+	usedBytes := uint64(used * float64(total))
+	return &disk.UsageStat{
+		Path:        path,
+		Fstype:      "ext4",
+		Total:       total,
+		Used:        usedBytes,
+		Free:        total - usedBytes,
+		UsedPercent: used * 100,
+	}
+}
+
+// stateAt returns the state of the instance after the given time since the start of the simulation,
+// healthy values slowly oscillate and the scenario repeats every period
+func (i *Instance) stateAt(elapsed time.Duration) state {
+	index := i.spec.Index
+	s := state{
+		memoryUsed:    0.35 + wave(elapsed, index)/100,
+		rootDiskUsed:  0.45,
+		dataDiskUsed:  0.30 + 0.01*float64(index%5),
+		storeDiskUsed: 0.20 + 0.02*float64(index%5),
+		load1:         0.6 + wave(elapsed, index)/5,
+		load5:         0.6 + wave(elapsed, index)/10,
+		load15:        0.6,
+		cpuUser:       8 + wave(elapsed, index),
+		cpuSystem:     3,
+		cpuIOWait:     0.5,
+		appUptime:     elapsed + time.Hour,
+		appMemory:     200 * mib,
+	}
+	cycle := int(elapsed / i.period)
+	progress := float64(elapsed%i.period) / float64(i.period)
+	switch i.scenario {
+	case config.ScenarioCrashloop:
+		// the process leaks memory for three quarters of the period until it is killed,
+		// it stays down for the last quarter and is restarted by Monit with a new pid
+		s.restarts = cycle
+		s.appUptime = time.Duration(progress * float64(i.period))
+		s.appMemory = uint64((200 + 1800*progress/0.75) * mib)
+		if progress >= 0.75 {
+			s.crashed = true
+			s.appUptime = 0
+			s.appMemory = 0
+		}
+		s.memoryUsed += float64(s.appMemory-min(s.appMemory, 200*mib)) / memoryTotal
+	case config.ScenarioDiskFill:
+		// the data disk fills up within 90% of the period, stays full and is cleaned up at the end of the period
+		s.dataDiskUsed += (1 - s.dataDiskUsed) * min(progress/0.9, 1)
+		s.cpuIOWait += 10 * min(progress/0.9, 1)
+	case config.ScenarioSwapStorm:
+		// the memory fills up in the first half of the period, then the instance swaps in the second half
+		s.memoryUsed += (0.98 - s.memoryUsed) * min(2*progress, 1)
+		s.swapUsed = 0.95 * max(2*progress-1, 0)
+		s.load1 += 12 * s.swapUsed
+		s.load5 += 8 * s.swapUsed
+		s.load15 += 4 * s.swapUsed
+		s.cpuSystem += 15 * s.swapUsed
+		s.cpuIOWait += 40 * s.swapUsed
+	}
+	s.memoryUsed = min(s.memoryUsed, 1)
+	return s
+}
+
+// wave returns a deterministic oscillation between -1 and 1 with a period of about 5 minutes,
+// phase shifted by the given seed
+func wave(elapsed time.Duration, seed int) float64 {
+	return roundTo(math.Sin(elapsed.Seconds()/50+float64(seed)), 2)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
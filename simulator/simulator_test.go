@@ -0,0 +1,131 @@
+package simulator
+
+import (
+	"boshi_exporter/collectors"
+	"boshi_exporter/config"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Date(2025, 5, 23, 11, 0, 0, 0, time.UTC)
+
+func newTestInstances(t *testing.T, scenario string) []*Instance {
+	instances, err := NewInstances(&config.SimulateContext{Instances: 2, Scenario: scenario, Period: 10 * time.Minute, Deployment: "simulation"}, testStart)
+	require.NoError(t, err)
+	return instances
+}
+
+// at sets the clock of the instance to the given time after the start of the simulation
+func at(instance *Instance, elapsed time.Duration) *Instance {
+	instance.now = func() time.Time { return testStart.Add(elapsed) }
+	return instance
+}
+
+func TestNewInstances(t *testing.T) {
+	instances := newTestInstances(t, config.ScenarioCrashloop)
+	require.Len(t, instances, 2)
+	spec, err := instances[1].Spec(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "simulation", spec.Deployment)
+	assert.Equal(t, 1, spec.Index)
+	assert.Equal(t, "z2", spec.AZ)
+	assert.Equal(t, "10.0.0.11", spec.IP())
+
+	_, err = NewInstances(&config.SimulateContext{Instances: 0, Scenario: config.ScenarioCrashloop, Period: time.Minute}, testStart)
+	assert.Error(t, err)
+	_, err = NewInstances(&config.SimulateContext{Instances: 1, Scenario: "meltdown", Period: time.Minute}, testStart)
+	assert.Error(t, err)
+}
+
+func TestInstance_Crashloop(t *testing.T) {
+	instances := newTestInstances(t, config.ScenarioCrashloop)
+	ctx := context.Background()
+
+	running, err := at(instances[0], 5*time.Minute).Monit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "running", running.Processes["app"].Status)
+	assert.Equal(t, 5*time.Minute, running.Processes["app"].Uptime)
+
+	crashed, err := at(instances[0], 8*time.Minute).Monit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Does not exist", crashed.Processes["app"].Status)
+	assert.Equal(t, "running", crashed.Processes["worker"].Status)
+
+	restarted, err := at(instances[0], 11*time.Minute).Monit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "running", restarted.Processes["app"].Status)
+	assert.NotEqual(t, running.Processes["app"].PID, restarted.Processes["app"].PID)
+
+	healthy, err := at(instances[1], 8*time.Minute).Monit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "running", healthy.Processes["app"].Status)
+}
+
+func TestInstance_DiskFill(t *testing.T) {
+	instances := newTestInstances(t, config.ScenarioDiskFill)
+	ctx := context.Background()
+
+	start, err := at(instances[0], 0).System(ctx)
+	require.NoError(t, err)
+	filling, err := at(instances[0], 4*time.Minute).System(ctx)
+	require.NoError(t, err)
+	full, err := at(instances[0], 9*time.Minute+30*time.Second).System(ctx)
+	require.NoError(t, err)
+	cleaned, err := at(instances[0], 10*time.Minute).System(ctx)
+	require.NoError(t, err)
+	assert.Less(t, start.Disks.DataDisk.UsedPercent, filling.Disks.DataDisk.UsedPercent)
+	assert.InDelta(t, 100, full.Disks.DataDisk.UsedPercent, 0.001)
+	assert.Equal(t, uint64(0), full.Disks.DataDisk.Free)
+	assert.Equal(t, start.Disks.DataDisk.UsedPercent, cleaned.Disks.DataDisk.UsedPercent)
+
+	healthy, err := at(instances[1], 9*time.Minute+30*time.Second).System(ctx)
+	require.NoError(t, err)
+	assert.Less(t, healthy.Disks.DataDisk.UsedPercent, 50.0)
+}
+
+func TestInstance_SwapStorm(t *testing.T) {
+	instances := newTestInstances(t, config.ScenarioSwapStorm)
+	ctx := context.Background()
+
+	filled, err := at(instances[0], 5*time.Minute).System(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 98, filled.Memory.VM.UsedPercent, 0.001)
+	assert.Zero(t, filled.Memory.SwapMemory.Used)
+
+	swapping, err := at(instances[0], 9*time.Minute).System(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, swapping.Memory.SwapMemory.UsedPercent, 50.0)
+	assert.Greater(t, swapping.Host.Load.Load1, filled.Host.Load.Load1)
+
+	// Monit reports the same condition as the system metrics
+	monit, err := at(instances[0], 9*time.Minute).Monit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, swapping.Memory.SwapMemory.UsedPercent, monit.System.SwapUsedPercent)
+	assert.Equal(t, swapping.Host.Load.Load1, monit.System.LoadAvg1)
+}
+
+func TestInstance_Collector(t *testing.T) {
+	instance := at(newTestInstances(t, config.ScenarioDiskFill)[0], 9*time.Minute+30*time.Second)
+	metricsContext := &config.MetricsContext{Namespace: "boshi", Schemas: []string{config.SchemaV2}}
+	collector, err := collectors.NewBoshInstanceCollector("boshi_exporter", "test", metricsContext, instance.Snapshots())
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	count, err := testutil.GatherAndCount(registry, "boshi_monit_process_status_info")
+	require.NoError(t, err)
+	assert.Equal(t, len(processNames), count)
+	expected := `
+# HELP boshi_system_disk_data_usage_ratio Used /var/vcap/data fraction (1=100%)
+# TYPE boshi_system_disk_data_usage_ratio gauge
+boshi_system_disk_data_usage_ratio{bosh_deployment="simulation",bosh_instance_az="z1",bosh_instance_id="00000000-0000-4000-8000-000000000000",bosh_instance_index="0",bosh_instance_name="simulated",bosh_name="",bosh_uuid="",environment=""} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "boshi_system_disk_data_usage_ratio"))
+}
@@ -0,0 +1,121 @@
+package sinks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const bufferFileSuffix = ".buf"
+
+// diskBuffer keeps payloads which could not be sent in files, the oldest files are dropped
+// when the total size exceeds maxBytes
+type diskBuffer struct {
+	dir      string
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+func newDiskBuffer(dir string, maxBytes int64) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("cannot create buffer directory '%s', error: %v", dir, err)
+	}
+	return &diskBuffer{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Add stores the payload as the newest buffer entry
+func (b *diskBuffer) Add(payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name := strconv.FormatInt(time.Now().UnixNano(), 10)
+	tmpPath := filepath.Join(b.dir, name+".tmp")
+	if err := os.WriteFile(tmpPath, payload, 0o640); err != nil {
+		return fmt.Errorf("cannot write buffer file '%s', error: %v", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(b.dir, name+bufferFileSuffix)); err != nil {
+		return fmt.Errorf("cannot write buffer file '%s', error: %v", tmpPath, err)
+	}
+	return b.trim()
+}
+
+// Flush sends buffered payloads from the oldest one, sent entries are removed.
+// It stops at the first send error, which is returned.
+func (b *diskBuffer) Flush(send func(payload []byte) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(b.dir, file.Name())
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read buffer file '%s', error: %v", path, err)
+		}
+		if err := send(payload); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("cannot remove buffer file '%s', error: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of buffered payloads
+func (b *diskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	files, _ := b.files()
+	return len(files)
+}
+
+// trim removes the oldest entries until the buffer fits into maxBytes
+func (b *diskBuffer) trim() error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size()
+	}
+	for _, file := range files {
+		if total <= b.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(b.dir, file.Name())); err != nil {
+			return fmt.Errorf("cannot remove buffer file '%s', error: %v", file.Name(), err)
+		}
+		total -= file.Size()
+	}
+	return nil
+}
+
+// files returns the buffer entries from the oldest one
+func (b *diskBuffer) files() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read buffer directory '%s', error: %v", b.dir, err)
+	}
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), bufferFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	// names are nanosecond timestamps of the same length, lexicographic order is chronological
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
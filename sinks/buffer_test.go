@@ -0,0 +1,46 @@
+package sinks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskBuffer_DropsOldestEntries(t *testing.T) {
+	buffer, err := newDiskBuffer(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	require.NoError(t, buffer.Add([]byte("first")))
+	require.NoError(t, buffer.Add([]byte("second")))
+	require.NoError(t, buffer.Add([]byte("third")))
+	assert.Equal(t, 1, buffer.Len())
+
+	var sent []string
+	require.NoError(t, buffer.Flush(func(payload []byte) error {
+		sent = append(sent, string(payload))
+		return nil
+	}))
+	assert.Equal(t, []string{"third"}, sent)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestDiskBuffer_FlushStopsOnError(t *testing.T) {
+	buffer, err := newDiskBuffer(t.TempDir(), 1024)
+	require.NoError(t, err)
+	require.NoError(t, buffer.Add([]byte("first")))
+	require.NoError(t, buffer.Add([]byte("second")))
+
+	var sent []string
+	err = buffer.Flush(func(payload []byte) error {
+		if string(payload) == "second" {
+			return errors.New("unavailable")
+		}
+		sent = append(sent, string(payload))
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"first"}, sent)
+	assert.Equal(t, 1, buffer.Len())
+}
@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+const graphiteNotAllowed = ";~!^="
+
+// GraphiteSink writes metrics in the Graphite plaintext protocol over TCP,
+// the metric paths are rendered from a template and optionally carry the labels as Graphite tags
+type GraphiteSink struct {
+	graphiteContext *config.GraphiteContext
+	pathTemplate    *template.Template
+}
+
+var _ Sink = (*GraphiteSink)(nil)
+
+func NewGraphiteSink(graphiteContext *config.GraphiteContext) (*GraphiteSink, error) {
+	pathTemplate, err := parseNameTemplate("graphite path", graphiteContext.PathTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphiteSink{graphiteContext: graphiteContext, pathTemplate: pathTemplate}, nil
+}
+
+func (s *GraphiteSink) Name() string {
+	return "graphite"
+}
+
+func (s *GraphiteSink) Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	lines := s.encodeLines(families, gathered)
+	if len(lines) == 0 {
+		return nil
+	}
+	dialer := net.Dialer{Timeout: s.graphiteContext.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.graphiteContext.Address)
+	if err != nil {
+		return fmt.Errorf("cannot connect to graphite '%s', error: %v", s.graphiteContext.Address, err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetWriteDeadline(time.Now().Add(s.graphiteContext.Timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(lines)
+	return err
+}
+
+// encodeLines encodes the samples as `path[;tag=value...] value timestamp` lines,
+// empty path segments left by missing labels are removed
+func (s *GraphiteSink) encodeLines(families []*dto.MetricFamily, gathered time.Time) []byte {
+	var lines bytes.Buffer
+	for _, smpl := range flattenFamilies(families, gathered) {
+		if math.IsNaN(smpl.Value) || math.IsInf(smpl.Value, 0) {
+			continue
+		}
+		path, err := executeNameTemplate(s.pathTemplate, &smpl)
+		if err != nil {
+			zap.L().Warn("Skipping graphite metric", zap.String("metric", smpl.Name), zap.Error(err))
+			continue
+		}
+		segments := strings.FieldsFunc(sanitize(path, graphiteNotAllowed), func(r rune) bool { return r == '.' })
+		if len(segments) == 0 {
+			continue
+		}
+		lines.WriteString(strings.Join(segments, "."))
+		if s.graphiteContext.Tags {
+			for _, name := range smpl.sortedLabelNames() {
+				if smpl.Labels[name] == "" {
+					continue
+				}
+				lines.WriteByte(';')
+				lines.WriteString(sanitize(name, graphiteNotAllowed))
+				lines.WriteByte('=')
+				lines.WriteString(sanitize(smpl.Labels[name], graphiteNotAllowed))
+			}
+		}
+		lines.WriteByte(' ')
+		lines.WriteString(strconv.FormatFloat(smpl.Value, 'g', -1, 64))
+		lines.WriteByte(' ')
+		lines.WriteString(strconv.FormatInt(smpl.Timestamp.Unix(), 10))
+		lines.WriteByte('\n')
+	}
+	return lines.Bytes()
+}
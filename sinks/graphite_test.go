@@ -0,0 +1,52 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendGraphite(t *testing.T, pathTemplate string, tags bool) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	sink, err := NewGraphiteSink(&config.GraphiteContext{
+		Address:      listener.Addr().String(),
+		PathTemplate: pathTemplate,
+		Tags:         tags,
+		Timeout:      time.Second,
+	})
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), families, time.Unix(1700000000, 0)))
+	return <-received
+}
+
+func TestGraphiteSink_Send(t *testing.T) {
+	lines := sendGraphite(t, "{{ .Labels.bosh_deployment }}.{{ .Labels.bosh_instance_name }}.{{ .Labels.process_name }}.{{ .Name }}", false)
+	assert.Equal(t, "test-dev.nats.boshi_monit_process_uptime_seconds 42 1700000000\n", lines)
+}
+
+func TestGraphiteSink_SendTags(t *testing.T) {
+	lines := sendGraphite(t, "{{ .Name }}", true)
+	assert.Equal(t, "boshi_monit_process_uptime_seconds;bosh_deployment=test-dev;process_name=nats 42 1700000000\n", lines)
+}
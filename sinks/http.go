@@ -0,0 +1,15 @@
+package sinks
+
+import (
+	"net/http"
+)
+
+// setAuth sets basic auth or bearer token authorization of the request
+func setAuth(req *http.Request, username, password, bearerToken string) {
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+}
@@ -0,0 +1,124 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// InfluxSink writes metrics as InfluxDB line protocol to the InfluxDB v2 write API,
+// every sample becomes a point with the labels as tags and a single "value" field
+type InfluxSink struct {
+	influxContext       *config.InfluxContext
+	client              *http.Client
+	writeURL            string
+	measurementTemplate *template.Template
+}
+
+var _ Sink = (*InfluxSink)(nil)
+
+func NewInfluxSink(influxContext *config.InfluxContext) (*InfluxSink, error) {
+	client, err := config.NewHTTPClient(influxContext.TLS, influxContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	measurementTemplate, err := parseNameTemplate("influx measurement", influxContext.MeasurementTemplate)
+	if err != nil {
+		return nil, err
+	}
+	writeURL, err := url.Parse(strings.TrimSuffix(influxContext.URL, "/") + "/api/v2/write")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse influx url '%s', error: %v", influxContext.URL, err)
+	}
+	query := writeURL.Query()
+	query.Set("org", influxContext.Org)
+	query.Set("bucket", influxContext.Bucket)
+	query.Set("precision", "ms")
+	writeURL.RawQuery = query.Encode()
+	return &InfluxSink{
+		influxContext:       influxContext,
+		client:              client,
+		writeURL:            writeURL.String(),
+		measurementTemplate: measurementTemplate,
+	}, nil
+}
+
+func (s *InfluxSink) Name() string {
+	return "influx"
+}
+
+func (s *InfluxSink) Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	body := s.encodeLines(families, gathered)
+	if len(body) == 0 {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.influxContext.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.influxContext.Token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("influx server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(message))
+}
+
+// encodeLines encodes the samples as `measurement,tag=value value=<float> <timestamp ms>` lines,
+// NaN and infinite values are not supported by InfluxDB and are skipped
+func (s *InfluxSink) encodeLines(families []*dto.MetricFamily, gathered time.Time) []byte {
+	var lines bytes.Buffer
+	for _, smpl := range flattenFamilies(families, gathered) {
+		if math.IsNaN(smpl.Value) || math.IsInf(smpl.Value, 0) {
+			continue
+		}
+		measurement, err := executeNameTemplate(s.measurementTemplate, &smpl)
+		if err != nil {
+			zap.L().Warn("Skipping influx metric", zap.String("metric", smpl.Name), zap.Error(err))
+			continue
+		}
+		lines.WriteString(influxMeasurementEscaper.Replace(measurement))
+		for _, name := range smpl.sortedLabelNames() {
+			if smpl.Labels[name] == "" {
+				continue
+			}
+			lines.WriteByte(',')
+			lines.WriteString(influxTagEscaper.Replace(name))
+			lines.WriteByte('=')
+			lines.WriteString(influxTagEscaper.Replace(smpl.Labels[name]))
+		}
+		lines.WriteString(" value=")
+		lines.WriteString(strconv.FormatFloat(smpl.Value, 'g', -1, 64))
+		lines.WriteByte(' ')
+		lines.WriteString(strconv.FormatInt(smpl.Timestamp.UnixMilli(), 10))
+		lines.WriteByte('\n')
+	}
+	return lines.Bytes()
+}
@@ -0,0 +1,62 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxSink_Send(t *testing.T) {
+	var request *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		request, body = r, string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink, err := NewInfluxSink(&config.InfluxContext{
+		URL:                 server.URL,
+		Org:                 "platform",
+		Bucket:              "bosh",
+		Token:               "secret",
+		MeasurementTemplate: "{{ .Labels.process_name }} {{ .Name }}",
+		Timeout:             time.Second,
+	})
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	gathered := time.UnixMilli(1700000000123)
+	require.NoError(t, sink.Send(context.Background(), families, gathered))
+
+	require.NotNil(t, request)
+	assert.Equal(t, "/api/v2/write", request.URL.Path)
+	assert.Equal(t, "platform", request.URL.Query().Get("org"))
+	assert.Equal(t, "bosh", request.URL.Query().Get("bucket"))
+	assert.Equal(t, "ms", request.URL.Query().Get("precision"))
+	assert.Equal(t, "Token secret", request.Header.Get("Authorization"))
+	assert.Equal(t, "nats\\ boshi_monit_process_uptime_seconds,bosh_deployment=test-dev,process_name=nats value=42 1700000000123\n", body)
+}
+
+func TestInfluxSink_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bucket not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	sink, err := NewInfluxSink(&config.InfluxContext{URL: server.URL, MeasurementTemplate: "{{ .Name }}", Timeout: time.Second})
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	err = sink.Send(context.Background(), families, time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bucket not found")
+}
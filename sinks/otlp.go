@@ -0,0 +1,244 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// OTLP protocols supported by the OTLPSink
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+const (
+	otlpHTTPPath = "/v1/metrics"
+	otlpGRPCPath = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+	otlpAggregationTemporalityCumulative = 2
+)
+
+// OTLPSink pushes gauges and counters as OpenTelemetry metrics over OTLP/HTTP or OTLP/gRPC.
+// The instance labels are moved from the data points to the resource attributes.
+type OTLPSink struct {
+	otlpContext    *config.OTLPContext
+	client         *http.Client
+	resource       map[string]string
+	instanceLabels map[string]bool
+	scopeName      string
+	scopeVersion   string
+}
+
+var _ Sink = (*OTLPSink)(nil)
+
+// NewOTLPSink creates the sink, instanceLabelNames are the names of the labels replaced by the resource attributes
+func NewOTLPSink(otlpContext *config.OTLPContext, resource map[string]string, instanceLabelNames []string, programName, programVersion string) (*OTLPSink, error) {
+	client, err := config.NewHTTPClient(otlpContext.TLS, otlpContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if otlpContext.Protocol == OTLPProtocolGRPC {
+		transport := client.Transport.(*http.Transport)
+		transport.ForceAttemptHTTP2 = true
+		// gRPC over plain text endpoints requires HTTP/2 with prior knowledge
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	instanceLabels := make(map[string]bool, len(instanceLabelNames))
+	for _, name := range instanceLabelNames {
+		instanceLabels[name] = true
+	}
+	return &OTLPSink{
+		otlpContext:    otlpContext,
+		client:         client,
+		resource:       resource,
+		instanceLabels: instanceLabels,
+		scopeName:      programName,
+		scopeVersion:   programVersion,
+	}, nil
+}
+
+func (s *OTLPSink) Name() string {
+	return "otlp"
+}
+
+func (s *OTLPSink) Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	request := s.encodeExportRequest(families, gathered)
+	if s.otlpContext.Protocol == OTLPProtocolGRPC {
+		return s.sendGRPC(ctx, request)
+	}
+	return s.sendHTTP(ctx, request)
+}
+
+func (s *OTLPSink) sendHTTP(ctx context.Context, request []byte) error {
+	url := strings.TrimSuffix(s.otlpContext.Endpoint, "/") + otlpHTTPPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	s.setHeaders(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (s *OTLPSink) sendGRPC(ctx context.Context, request []byte) error {
+	// gRPC message framing: compression flag and big endian message length
+	body := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	body = append(body, request...)
+
+	url := strings.TrimSuffix(s.otlpContext.Endpoint, "/") + otlpGRPCPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	s.setHeaders(req)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// trailers are available after the body is read
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OTLP gRPC server returned HTTP status %s", resp.Status)
+	}
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		// trailers-only responses carry the status in the headers
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return fmt.Errorf("OTLP gRPC server returned status %s: %s", status, message)
+	}
+	return nil
+}
+
+func (s *OTLPSink) setHeaders(req *http.Request) {
+	for name, value := range s.otlpContext.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// encodeExportRequest encodes the opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest message
+// with a single resource and scope, gauges and untyped metrics are mapped to OTLP gauges, counters to cumulative sums
+func (s *OTLPSink) encodeExportRequest(families []*dto.MetricFamily, gathered time.Time) []byte {
+	var resource []byte
+	for _, key := range sortedKeys(s.resource) {
+		resource = appendMessage(resource, 1, appendKeyValue(nil, key, s.resource[key]))
+	}
+
+	var scope []byte
+	scope = appendString(scope, 1, s.scopeName)
+	scope = appendString(scope, 2, s.scopeVersion)
+
+	var scopeMetrics []byte
+	scopeMetrics = appendMessage(scopeMetrics, 1, scope)
+	for _, family := range families {
+		if metric := s.encodeMetric(family, gathered); metric != nil {
+			scopeMetrics = appendMessage(scopeMetrics, 2, metric)
+		}
+	}
+
+	var resourceMetrics []byte
+	resourceMetrics = appendMessage(resourceMetrics, 1, resource)
+	resourceMetrics = appendMessage(resourceMetrics, 2, scopeMetrics)
+
+	return appendMessage(nil, 1, resourceMetrics)
+}
+
+// encodeMetric encodes the opentelemetry.proto.metrics.v1.Metric message, nil for unsupported metric types
+func (s *OTLPSink) encodeMetric(family *dto.MetricFamily, gathered time.Time) []byte {
+	var dataPoints []byte
+	for _, metric := range family.GetMetric() {
+		var value float64
+		switch family.GetType() {
+		case dto.MetricType_GAUGE:
+			value = metric.GetGauge().GetValue()
+		case dto.MetricType_UNTYPED:
+			value = metric.GetUntyped().GetValue()
+		case dto.MetricType_COUNTER:
+			value = metric.GetCounter().GetValue()
+		default:
+			return nil
+		}
+		timestamp := gathered
+		if metric.TimestampMs != nil {
+			timestamp = time.UnixMilli(metric.GetTimestampMs())
+		}
+		var dataPoint []byte
+		dataPoint = protowire.AppendTag(dataPoint, 3, protowire.Fixed64Type)
+		dataPoint = protowire.AppendFixed64(dataPoint, uint64(timestamp.UnixNano()))
+		dataPoint = protowire.AppendTag(dataPoint, 4, protowire.Fixed64Type)
+		dataPoint = protowire.AppendFixed64(dataPoint, math.Float64bits(value))
+		for _, pair := range metric.GetLabel() {
+			if !s.instanceLabels[pair.GetName()] {
+				dataPoint = appendMessage(dataPoint, 7, appendKeyValue(nil, pair.GetName(), pair.GetValue()))
+			}
+		}
+		dataPoints = appendMessage(dataPoints, 1, dataPoint)
+	}
+
+	var result []byte
+	result = appendString(result, 1, family.GetName())
+	result = appendString(result, 2, family.GetHelp())
+	if family.GetType() == dto.MetricType_COUNTER {
+		sum := dataPoints
+		sum = protowire.AppendTag(sum, 2, protowire.VarintType)
+		sum = protowire.AppendVarint(sum, otlpAggregationTemporalityCumulative)
+		sum = protowire.AppendTag(sum, 3, protowire.VarintType)
+		sum = protowire.AppendVarint(sum, 1)
+		return appendMessage(result, 7, sum)
+	}
+	return appendMessage(result, 5, dataPoints)
+}
+
+// appendKeyValue encodes the opentelemetry.proto.common.v1.KeyValue message with a string value
+func appendKeyValue(b []byte, key, value string) []byte {
+	b = appendString(b, 1, key)
+	return appendMessage(b, 2, appendString(nil, 1, value))
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
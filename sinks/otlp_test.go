@@ -0,0 +1,178 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoField is a decoded protobuf field, bytes holds length-delimited values, number holds the others
+type protoField struct {
+	bytes  []byte
+	number uint64
+}
+
+func decodeFields(b []byte) map[protowire.Number][]protoField {
+	fields := make(map[protowire.Number][]protoField)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		var field protoField
+		switch typ {
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			field.number, n = protowire.ConsumeFixed64(b)
+		default:
+			field.number, n = protowire.ConsumeVarint(b)
+		}
+		b = b[n:]
+		fields[num] = append(fields[num], field)
+	}
+	return fields
+}
+
+func decodeKeyValues(fields []protoField) map[string]string {
+	result := make(map[string]string)
+	for _, field := range fields {
+		keyValue := decodeFields(field.bytes)
+		value := decodeFields(keyValue[2][0].bytes)
+		result[string(keyValue[1][0].bytes)] = string(value[1][0].bytes)
+	}
+	return result
+}
+
+// otlpMetric is the decoded content of a single gauge metric with one data point
+type otlpMetric struct {
+	resource   map[string]string
+	name       string
+	attributes map[string]string
+	value      float64
+}
+
+func decodeExportRequest(t *testing.T, request []byte) otlpMetric {
+	resourceMetrics := decodeFields(decodeFields(request)[1][0].bytes)
+	resource := decodeFields(resourceMetrics[1][0].bytes)
+	scopeMetrics := decodeFields(resourceMetrics[2][0].bytes)
+	require.Len(t, scopeMetrics[2], 1)
+	metric := decodeFields(scopeMetrics[2][0].bytes)
+	gauge := decodeFields(metric[5][0].bytes)
+	dataPoint := decodeFields(gauge[1][0].bytes)
+	return otlpMetric{
+		resource:   decodeKeyValues(resource[1]),
+		name:       string(metric[1][0].bytes),
+		attributes: decodeKeyValues(dataPoint[7]),
+		value:      math.Float64frombits(dataPoint[4][0].number),
+	}
+}
+
+func newTestOTLPSink(t *testing.T, otlpContext *config.OTLPContext) *OTLPSink {
+	metricsContext := &config.MetricsContext{Environment: "prod"}
+	spec := &fetchers.InstanceSpec{Deployment: "test-dev", Name: "nats", Index: 1, ID: "b36ca4c9", AZ: "z2"}
+	sink, err := NewOTLPSink(otlpContext, NewResourceAttributes(metricsContext, spec), []string{"bosh_deployment"}, "boshi_exporter", "test")
+	require.NoError(t, err)
+	return sink
+}
+
+func assertOTLPMetric(t *testing.T, metric otlpMetric) {
+	assert.Equal(t, "b36ca4c9", metric.resource["service.instance.id"])
+	assert.Equal(t, "prod", metric.resource["deployment.environment"])
+	assert.Equal(t, "z2", metric.resource["cloud.availability_zone"])
+	assert.Equal(t, "test-dev", metric.resource["bosh.deployment"])
+	assert.Equal(t, "boshi_monit_process_uptime_seconds", metric.name)
+	// instance labels are moved to the resource
+	assert.Equal(t, map[string]string{"process_name": "nats"}, metric.attributes)
+	assert.Equal(t, 42.0, metric.value)
+}
+
+func TestOTLPSink_SendHTTP(t *testing.T) {
+	var received []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		received, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	sink := newTestOTLPSink(t, &config.OTLPContext{
+		Endpoint: server.URL,
+		Protocol: OTLPProtocolHTTP,
+		Timeout:  time.Second,
+		Headers:  map[string]string{"Authorization": "Api-Token secret"},
+	})
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), families, time.Now()))
+
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "Api-Token secret", headers.Get("Authorization"))
+	assertOTLPMetric(t, decodeExportRequest(t, received))
+}
+
+func TestOTLPSink_SendGRPC(t *testing.T) {
+	var received []byte
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export", r.URL.Path)
+		assert.Equal(t, 2, r.ProtoMajor)
+		body, _ := io.ReadAll(r.Body)
+		length := binary.BigEndian.Uint32(body[1:5])
+		received = body[5 : 5+length]
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		// empty ExportMetricsServiceResponse
+		_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sink := newTestOTLPSink(t, &config.OTLPContext{
+		Endpoint: server.URL,
+		Protocol: OTLPProtocolGRPC,
+		Timeout:  time.Second,
+		TLS:      &config.TLSContext{InsecureSkipVerify: true},
+	})
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), families, time.Now()))
+
+	assertOTLPMetric(t, decodeExportRequest(t, received))
+}
+
+func TestOTLPSink_SendGRPCError(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "16")
+		w.Header().Set("Grpc-Message", "unauthenticated")
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sink := newTestOTLPSink(t, &config.OTLPContext{
+		Endpoint: server.URL,
+		Protocol: OTLPProtocolGRPC,
+		Timeout:  time.Second,
+		TLS:      &config.TLSContext{InsecureSkipVerify: true},
+	})
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	err = sink.Send(context.Background(), families, time.Now())
+	assert.ErrorContains(t, err, "unauthenticated")
+}
@@ -0,0 +1,88 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// PushgatewaySink pushes metrics to a Pushgateway group of the Bosh instance,
+// on shutdown it makes a final push or deletes the group
+type PushgatewaySink struct {
+	pushgatewayContext *config.PushgatewayContext
+	client             *http.Client
+	grouping           map[string]string
+}
+
+var _ ShutdownSink = (*PushgatewaySink)(nil)
+
+// NewPushgatewaySink creates the sink, the grouping labels identify the group of the Bosh instance
+// and are removed from the pushed metrics
+func NewPushgatewaySink(pushgatewayContext *config.PushgatewayContext, grouping map[string]string) (*PushgatewaySink, error) {
+	client, err := config.NewHTTPClient(pushgatewayContext.TLS, pushgatewayContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &PushgatewaySink{
+		pushgatewayContext: pushgatewayContext,
+		client:             client,
+		// the Pushgateway attaches the grouping labels to all metrics of the group
+		grouping: grouping,
+	}, nil
+}
+
+func (s *PushgatewaySink) Name() string {
+	return "pushgateway"
+}
+
+func (s *PushgatewaySink) Send(ctx context.Context, families []*dto.MetricFamily, _ time.Time) error {
+	return s.pusher(families).PushContext(ctx)
+}
+
+// Shutdown deletes the group if configured, otherwise it pushes the final metrics
+func (s *PushgatewaySink) Shutdown(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	if s.pushgatewayContext.DeleteOnShutdown {
+		return s.pusher(nil).Delete()
+	}
+	return s.Send(ctx, families, gathered)
+}
+
+func (s *PushgatewaySink) pusher(families []*dto.MetricFamily) *push.Pusher {
+	families = s.withoutGroupingLabels(families)
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, nil
+	})
+	pusher := push.New(s.pushgatewayContext.URL, s.pushgatewayContext.Job).Gatherer(gatherer).Client(s.client)
+	for _, name := range sortedKeys(s.grouping) {
+		pusher = pusher.Grouping(name, s.grouping[name])
+	}
+	if s.pushgatewayContext.BasicAuthUsername != "" {
+		pusher = pusher.BasicAuth(s.pushgatewayContext.BasicAuthUsername, s.pushgatewayContext.BasicAuthPassword)
+	}
+	return pusher
+}
+
+// withoutGroupingLabels removes the grouping labels from the metrics, the Pushgateway rejects metrics containing them
+func (s *PushgatewaySink) withoutGroupingLabels(families []*dto.MetricFamily) []*dto.MetricFamily {
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		family = proto.Clone(family).(*dto.MetricFamily)
+		for _, metric := range family.GetMetric() {
+			labels := metric.Label[:0]
+			for _, pair := range metric.GetLabel() {
+				if _, ok := s.grouping[pair.GetName()]; !ok {
+					labels = append(labels, pair)
+				}
+			}
+			metric.Label = labels
+		}
+		result = append(result, family)
+	}
+	return result
+}
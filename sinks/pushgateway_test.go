@@ -0,0 +1,109 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pushgatewayRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+func newPushgatewayStub(t *testing.T) (*httptest.Server, func() []pushgatewayRequest) {
+	var mu sync.Mutex
+	var requests []pushgatewayRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, pushgatewayRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []pushgatewayRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pushgatewayRequest(nil), requests...)
+	}
+}
+
+func newTestPushgatewaySink(t *testing.T, url string, deleteOnShutdown bool) *PushgatewaySink {
+	sink, err := NewPushgatewaySink(&config.PushgatewayContext{
+		URL:              url,
+		Job:              "boshi_exporter",
+		Timeout:          time.Second,
+		DeleteOnShutdown: deleteOnShutdown,
+	}, map[string]string{"bosh_deployment": "test-dev", "bosh_instance_name": "nats", "bosh_instance_index": "1"})
+	require.NoError(t, err)
+	return sink
+}
+
+// assertGroupPath checks the grouping key path, the order of the grouping labels is not defined
+func assertGroupPath(t *testing.T, path string) {
+	assert.True(t, strings.HasPrefix(path, "/metrics/job/boshi_exporter/"), path)
+	assert.Contains(t, path, "/bosh_deployment/test-dev")
+	assert.Contains(t, path, "/bosh_instance_name/nats")
+	assert.Contains(t, path, "/bosh_instance_index/1")
+}
+
+func TestPushgatewaySink_Send(t *testing.T) {
+	server, requests := newPushgatewayStub(t)
+	sink := newTestPushgatewaySink(t, server.URL, false)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Send(context.Background(), families, time.Now()))
+
+	received := requests()
+	require.Len(t, received, 1)
+	assert.Equal(t, http.MethodPut, received[0].method)
+	assertGroupPath(t, received[0].path)
+	// the grouping labels are attached by the Pushgateway
+	assert.NotContains(t, received[0].body, "test-dev")
+	assert.Contains(t, received[0].body, "nats")
+	// the gathered families are left untouched
+	assert.Len(t, families[0].GetMetric()[0].GetLabel(), 2)
+}
+
+func TestPushgatewaySink_RunPushesOnShutdown(t *testing.T) {
+	server, requests := newPushgatewayStub(t)
+	sink := newTestPushgatewaySink(t, server.URL, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, sink, newTestRegistry(t), time.Hour, time.Second)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return len(requests()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	received := requests()
+	require.Len(t, received, 2)
+	assert.Equal(t, http.MethodPut, received[1].method)
+}
+
+func TestPushgatewaySink_DeleteOnShutdown(t *testing.T) {
+	server, requests := newPushgatewayStub(t)
+	sink := newTestPushgatewaySink(t, server.URL, true)
+
+	require.NoError(t, sink.Shutdown(context.Background(), nil, time.Now()))
+
+	received := requests()
+	require.Len(t, received, 1)
+	assert.Equal(t, http.MethodDelete, received[0].method)
+	assertGroupPath(t, received[0].path)
+}
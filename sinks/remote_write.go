@@ -0,0 +1,180 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteSink pushes metrics as snappy-compressed protobuf Prometheus remote_write requests
+type RemoteWriteSink struct {
+	remoteWriteContext *config.RemoteWriteContext
+	client             *http.Client
+	buffer             *diskBuffer // nil if buffering is disabled
+}
+
+var _ Sink = (*RemoteWriteSink)(nil)
+
+// nonRecoverableError marks requests rejected by the receiver which must not be retried
+type nonRecoverableError struct {
+	err error
+}
+
+func (e *nonRecoverableError) Error() string { return e.err.Error() }
+func (e *nonRecoverableError) Unwrap() error { return e.err }
+
+func NewRemoteWriteSink(remoteWriteContext *config.RemoteWriteContext) (*RemoteWriteSink, error) {
+	client, err := config.NewHTTPClient(remoteWriteContext.TLS, remoteWriteContext.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	sink := &RemoteWriteSink{remoteWriteContext: remoteWriteContext, client: client}
+	if remoteWriteContext.BufferDir != "" {
+		sink.buffer, err = newDiskBuffer(remoteWriteContext.BufferDir, remoteWriteContext.BufferMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sink, nil
+}
+
+func (s *RemoteWriteSink) Name() string {
+	return "remote_write"
+}
+
+// Send pushes the buffered requests first, to keep the samples in order, and then the current one.
+// Requests which could not be sent after all retries are buffered.
+func (s *RemoteWriteSink) Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	body := snappy.Encode(nil, encodeWriteRequest(flattenFamilies(families, gathered)))
+	if s.buffer != nil {
+		err := s.buffer.Flush(func(payload []byte) error {
+			err := s.sendWithRetries(ctx, payload)
+			var nonRecoverable *nonRecoverableError
+			if errors.As(err, &nonRecoverable) {
+				zap.L().Warn("Dropping buffered remote_write request", zap.Error(err))
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return s.bufferRequest(body, err)
+		}
+	}
+	err := s.sendWithRetries(ctx, body)
+	var nonRecoverable *nonRecoverableError
+	if err != nil && s.buffer != nil && !errors.As(err, &nonRecoverable) {
+		return s.bufferRequest(body, err)
+	}
+	return err
+}
+
+func (s *RemoteWriteSink) bufferRequest(body []byte, sendErr error) error {
+	if err := s.buffer.Add(body); err != nil {
+		return fmt.Errorf("remote_write failed: %w, buffering failed: %v", sendErr, err)
+	}
+	return fmt.Errorf("remote_write failed, request buffered: %w", sendErr)
+}
+
+func (s *RemoteWriteSink) sendWithRetries(ctx context.Context, body []byte) error {
+	backoff := s.remoteWriteContext.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.send(ctx, body)
+		var nonRecoverable *nonRecoverableError
+		if err == nil || errors.As(err, &nonRecoverable) || attempt >= s.remoteWriteContext.MaxRetries {
+			return err
+		}
+		zap.L().Debug("Retrying remote_write request", zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.remoteWriteContext.MaxBackoff)
+	}
+}
+
+func (s *RemoteWriteSink) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.remoteWriteContext.URL, bytes.NewReader(body))
+	if err != nil {
+		return &nonRecoverableError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	setAuth(req, s.remoteWriteContext.BasicAuthUsername, s.remoteWriteContext.BasicAuthPassword, s.remoteWriteContext.BearerToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("remote_write server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(message))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return &nonRecoverableError{err: err}
+}
+
+// metricNameLabel holds the metric name in remote_write series
+const metricNameLabel = "__name__"
+
+// encodeWriteRequest encodes samples as the prometheus.WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(samples []sample) []byte {
+	var request []byte
+	for _, s := range samples {
+		var series []byte
+		// labels must be sorted by name, including __name__ which sorts after uppercase label names
+		names := append(s.sortedLabelNames(), metricNameLabel)
+		sort.Strings(names)
+		for _, name := range names {
+			if name == metricNameLabel {
+				series = appendLabel(series, name, s.Name)
+			} else {
+				series = appendLabel(series, name, s.Labels[name])
+			}
+		}
+		var value []byte
+		value = protowire.AppendTag(value, 1, protowire.Fixed64Type)
+		value = protowire.AppendFixed64(value, math.Float64bits(s.Value))
+		value = protowire.AppendTag(value, 2, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(s.Timestamp.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, value)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return request
+}
+
+func appendLabel(series []byte, name, value string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, name)
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, value)
+	series = protowire.AppendTag(series, 1, protowire.BytesType)
+	return protowire.AppendBytes(series, label)
+}
@@ -0,0 +1,226 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type receivedSeries struct {
+	labels    map[string]string
+	names     []string // label names in the order of the request
+	value     float64
+	timestamp int64
+}
+
+// remoteWriteReceiver records the decoded series of remote_write requests, failing the first failures requests
+type remoteWriteReceiver struct {
+	mu       sync.Mutex
+	failures int
+	requests [][]receivedSeries
+	headers  []http.Header
+}
+
+func (r *remoteWriteReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	compressed, _ := io.ReadAll(req.Body)
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.requests = append(r.requests, decodeWriteRequest(body))
+	r.headers = append(r.headers, req.Header.Clone())
+}
+
+func (r *remoteWriteReceiver) received() [][]receivedSeries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests
+}
+
+func decodeWriteRequest(b []byte) []receivedSeries {
+	var result []receivedSeries
+	forEachField(b, func(num protowire.Number, series []byte) {
+		s := receivedSeries{labels: map[string]string{}}
+		forEachField(series, func(num protowire.Number, field []byte) {
+			if num == 1 {
+				var name, value string
+				forEachField(field, func(num protowire.Number, v []byte) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.labels[name] = value
+				s.names = append(s.names, name)
+				return
+			}
+			for len(field) > 0 {
+				num, typ, n := protowire.ConsumeTag(field)
+				field = field[n:]
+				if num == 1 && typ == protowire.Fixed64Type {
+					v, n := protowire.ConsumeFixed64(field)
+					s.value = math.Float64frombits(v)
+					field = field[n:]
+				} else {
+					v, n := protowire.ConsumeVarint(field)
+					s.timestamp = int64(v)
+					field = field[n:]
+				}
+			}
+		})
+		result = append(result, s)
+	})
+	return result
+}
+
+// forEachField calls fn for every length-delimited field of the message
+func forEachField(b []byte, fn func(num protowire.Number, value []byte)) {
+	for len(b) > 0 {
+		num, _, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		value, n := protowire.ConsumeBytes(b)
+		b = b[n:]
+		fn(num, value)
+	}
+}
+
+func newTestRegistry(t *testing.T) *prometheus.Registry {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   "boshi",
+		Subsystem:   "monit",
+		Name:        "process_uptime_seconds",
+		Help:        "Monit process uptime since last start (seconds)",
+		ConstLabels: prometheus.Labels{"bosh_deployment": "test-dev"},
+	}, []string{"process_name"})
+	gauge.WithLabelValues("nats").Set(42)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(gauge))
+	return registry
+}
+
+func newTestRemoteWriteContext(url string) *config.RemoteWriteContext {
+	return &config.RemoteWriteContext{
+		URL:         url,
+		Timeout:     time.Second,
+		BearerToken: "secret",
+		MaxRetries:  2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}
+}
+
+func TestRemoteWriteSink_Send(t *testing.T) {
+	receiver := &remoteWriteReceiver{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sink, err := NewRemoteWriteSink(newTestRemoteWriteContext(server.URL))
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	gathered := time.UnixMilli(1748000000000)
+
+	// the first two attempts fail and are retried
+	require.NoError(t, sink.Send(context.Background(), families, gathered))
+
+	requests := receiver.received()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0], 1)
+	series := requests[0][0]
+	assert.Equal(t, map[string]string{
+		"__name__":        "boshi_monit_process_uptime_seconds",
+		"bosh_deployment": "test-dev",
+		"process_name":    "nats",
+	}, series.labels)
+	assert.Equal(t, 42.0, series.value)
+	assert.Equal(t, gathered.UnixMilli(), series.timestamp)
+
+	headers := receiver.headers[0]
+	assert.Equal(t, "snappy", headers.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", headers.Get("Authorization"))
+}
+
+func TestRemoteWriteSink_BuffersDuringOutage(t *testing.T) {
+	receiver := &remoteWriteReceiver{failures: 3}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	remoteWriteContext := newTestRemoteWriteContext(server.URL)
+	remoteWriteContext.BufferDir = t.TempDir()
+	remoteWriteContext.BufferMaxBytes = 1024 * 1024
+	sink, err := NewRemoteWriteSink(remoteWriteContext)
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	first := time.UnixMilli(1748000000000)
+	assert.Error(t, sink.Send(context.Background(), families, first))
+	assert.Equal(t, 1, sink.buffer.Len())
+	assert.Empty(t, receiver.received())
+
+	// the buffered request is sent before the current one
+	second := first.Add(time.Minute)
+	require.NoError(t, sink.Send(context.Background(), families, second))
+	assert.Equal(t, 0, sink.buffer.Len())
+	requests := receiver.received()
+	require.Len(t, requests, 2)
+	assert.Equal(t, first.UnixMilli(), requests[0][0].timestamp)
+	assert.Equal(t, second.UnixMilli(), requests[1][0].timestamp)
+}
+
+func TestRemoteWriteSink_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	remoteWriteContext := newTestRemoteWriteContext(server.URL)
+	remoteWriteContext.BufferDir = t.TempDir()
+	remoteWriteContext.BufferMaxBytes = 1024 * 1024
+	sink, err := NewRemoteWriteSink(remoteWriteContext)
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	assert.Error(t, sink.Send(context.Background(), families, time.Now()))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, sink.buffer.Len())
+}
+
+func TestEncodeWriteRequest_SortsNameLabel(t *testing.T) {
+	request := encodeWriteRequest([]sample{{
+		Name:   "boshi_monit_process_uptime_seconds",
+		Labels: map[string]string{"Env": "prod", "process_name": "nats", "bosh_deployment": "test-dev"},
+		Value:  42,
+	}})
+
+	series := decodeWriteRequest(request)
+	require.Len(t, series, 1)
+	// uppercase label names sort before __name__
+	assert.Equal(t, []string{"Env", "__name__", "bosh_deployment", "process_name"}, series[0].names)
+	assert.Equal(t, "boshi_monit_process_uptime_seconds", series[0].labels["__name__"])
+}
@@ -0,0 +1,32 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"boshi_exporter/fetchers"
+	"strconv"
+)
+
+// NewResourceAttributes maps the Bosh instance to OpenTelemetry resource attributes,
+// the semantic conventions are used where they exist, empty values are omitted
+func NewResourceAttributes(metricsContext *config.MetricsContext, spec *fetchers.InstanceSpec) map[string]string {
+	attributes := map[string]string{
+		"service.name":            spec.Name,
+		"service.instance.id":     spec.ID,
+		"deployment.environment":  metricsContext.Environment,
+		"cloud.availability_zone": spec.AZ,
+		"bosh.director.name":      metricsContext.BoshName,
+		"bosh.director.uuid":      metricsContext.BoshUuid,
+		"bosh.deployment":         spec.Deployment,
+		"bosh.instance.name":      spec.Name,
+		"bosh.instance.index":     strconv.Itoa(spec.Index),
+	}
+	for name, value := range metricsContext.ExtraLabels {
+		attributes[name] = value
+	}
+	for name, value := range attributes {
+		if value == "" {
+			delete(attributes, name)
+		}
+	}
+	return attributes
+}
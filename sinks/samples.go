@@ -0,0 +1,102 @@
+package sinks
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// sample is a single flattened series value of a gathered metric family
+type sample struct {
+	Name      string            // series name, e.g. with the _bucket, _sum or _count suffix
+	Family    *dto.MetricFamily // family the sample belongs to
+	Labels    map[string]string // series labels without the metric name
+	Value     float64
+	Timestamp time.Time
+}
+
+// sortedLabelNames returns the label names of the sample in lexicographic order
+func (s *sample) sortedLabelNames() []string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flattenFamilies converts metric families into samples the way the Prometheus text format exposes them,
+// the gathered time is used for metrics without an explicit timestamp
+func flattenFamilies(families []*dto.MetricFamily, gathered time.Time) []sample {
+	var samples []sample
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			timestamp := gathered
+			if metric.TimestampMs != nil {
+				timestamp = time.UnixMilli(metric.GetTimestampMs())
+			}
+			add := func(name string, value float64, extraLabels ...string) {
+				sampleLabels := labels
+				if len(extraLabels) > 0 {
+					sampleLabels = make(map[string]string, len(labels)+len(extraLabels)/2)
+					for k, v := range labels {
+						sampleLabels[k] = v
+					}
+					for i := 0; i+1 < len(extraLabels); i += 2 {
+						sampleLabels[extraLabels[i]] = extraLabels[i+1]
+					}
+				}
+				samples = append(samples, sample{Name: name, Family: family, Labels: sampleLabels, Value: value, Timestamp: timestamp})
+			}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				add(name, metric.GetGauge().GetValue())
+			case dto.MetricType_COUNTER:
+				add(name, metric.GetCounter().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, metric.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					add(name, q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", summary.GetSampleSum())
+				add(name+"_count", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				hasInf := false
+				for _, b := range histogram.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), +1) {
+						hasInf = true
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				if !hasInf {
+					add(name+"_bucket", float64(histogram.GetSampleCount()), "le", "+Inf")
+				}
+				add(name+"_sum", histogram.GetSampleSum())
+				add(name+"_count", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+	return samples
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
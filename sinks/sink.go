@@ -0,0 +1,70 @@
+package sinks
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+// Sink sends gathered metric families to an external system
+type Sink interface {
+	// Name returns the sink name used in logs
+	Name() string
+	// Send delivers the metric families gathered at the given time
+	Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error
+}
+
+// ShutdownSink is implemented by sinks which act on shutdown, e.g. push the final metrics
+type ShutdownSink interface {
+	Sink
+	// Shutdown is called once with the metrics gathered after the sink loop has been stopped
+	Shutdown(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error
+}
+
+// Run gathers metrics and sends them to the sink immediately and then every interval until ctx is done,
+// then shuts down a ShutdownSink within the shutdown timeout
+func Run(ctx context.Context, sink Sink, gatherer prometheus.Gatherer, interval, shutdownTimeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		Push(ctx, sink, gatherer)
+		select {
+		case <-ctx.Done():
+			if shutdownSink, ok := sink.(ShutdownSink); ok {
+				shutdown(ctx, shutdownSink, gatherer, shutdownTimeout)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push gathers metrics once and sends them to the sink, errors are logged
+func Push(ctx context.Context, sink Sink, gatherer prometheus.Gatherer) {
+	families, gathered := gather(sink, gatherer)
+	if err := sink.Send(ctx, families, gathered); err != nil {
+		zap.L().Error("Failed to send metrics", zap.String("sink", sink.Name()), zap.Error(err))
+	}
+}
+
+func shutdown(ctx context.Context, sink ShutdownSink, gatherer prometheus.Gatherer, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	families, gathered := gather(sink, gatherer)
+	if err := sink.Shutdown(ctx, families, gathered); err != nil {
+		zap.L().Error("Failed to shut down metrics sink", zap.String("sink", sink.Name()), zap.Error(err))
+	}
+}
+
+func gather(sink Sink, gatherer prometheus.Gatherer) ([]*dto.MetricFamily, time.Time) {
+	gathered := time.Now()
+	families, err := gatherer.Gather()
+	if err != nil {
+		// Gather returns the metrics which could be gathered together with the error
+		zap.L().Warn("Failed to gather some metrics", zap.String("sink", sink.Name()), zap.Error(err))
+	}
+	return families, gathered
+}
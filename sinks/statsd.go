@@ -0,0 +1,128 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"text/template"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+// StatsD flavors supported by the StatsDSink
+const (
+	StatsDFlavorStatsD    = "statsd"
+	StatsDFlavorDogStatsD = "dogstatsd"
+)
+
+const (
+	// statsDMaxUDPPacketSize keeps the datagrams below the usual network MTU
+	statsDMaxUDPPacketSize = 1432
+	// statsDMaxUnixPacketSize is the default DogStatsD buffer size of unix sockets
+	statsDMaxUnixPacketSize = 8192
+
+	statsDNotAllowed = ":|@#,"
+)
+
+// Default name templates of the flavors, the statsd flavor has no tags, so the label values
+// sorted by label name are appended to the name to keep the series apart
+const (
+	statsDDefaultNameTemplate = "{{ .Name }}"
+	statsDTaglessNameTemplate = "{{ .Name }}{{ range .Labels }}{{ if . }}.{{ . }}{{ end }}{{ end }}"
+)
+
+// StatsDSink sends gauges as StatsD datagrams over UDP or a unix datagram socket,
+// the DogStatsD flavor sends the metric labels as tags
+type StatsDSink struct {
+	statsdContext *config.StatsDContext
+	nameTemplate  *template.Template
+}
+
+var _ Sink = (*StatsDSink)(nil)
+
+// NewStatsDSink creates the sink, an empty name template selects the default template of the flavor
+func NewStatsDSink(statsdContext *config.StatsDContext) (*StatsDSink, error) {
+	text := statsdContext.NameTemplate
+	if text == "" {
+		text = statsDDefaultNameTemplate
+		if statsdContext.Flavor == StatsDFlavorStatsD {
+			text = statsDTaglessNameTemplate
+		}
+	}
+	nameTemplate, err := parseNameTemplate("statsd name", text)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsDSink{statsdContext: statsdContext, nameTemplate: nameTemplate}, nil
+}
+
+func (s *StatsDSink) Name() string {
+	return "statsd"
+}
+
+func (s *StatsDSink) Send(ctx context.Context, families []*dto.MetricFamily, gathered time.Time) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.statsdContext.Network, s.statsdContext.Address)
+	if err != nil {
+		return fmt.Errorf("cannot connect to statsd '%s', error: %v", s.statsdContext.Address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	maxPacketSize := statsDMaxUDPPacketSize
+	if s.statsdContext.Network == "unixgram" {
+		maxPacketSize = statsDMaxUnixPacketSize
+	}
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+	for _, line := range s.encodeLines(families, gathered) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// encodeLines encodes the gauges as `name:value|g` lines, with `|#tag:value,...` in the DogStatsD flavor
+func (s *StatsDSink) encodeLines(families []*dto.MetricFamily, gathered time.Time) []string {
+	var lines []string
+	for _, smpl := range flattenFamilies(families, gathered) {
+		if smpl.Family.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		name, err := executeNameTemplate(s.nameTemplate, &smpl)
+		if err != nil {
+			zap.L().Warn("Skipping statsd metric", zap.String("metric", smpl.Name), zap.Error(err))
+			continue
+		}
+		line := sanitize(name, statsDNotAllowed) + ":" + strconv.FormatFloat(smpl.Value, 'f', -1, 64) + "|g"
+		if s.statsdContext.Flavor == StatsDFlavorDogStatsD && len(smpl.Labels) > 0 {
+			line += "|#"
+			for i, label := range smpl.sortedLabelNames() {
+				if i > 0 {
+					line += ","
+				}
+				line += sanitize(label, statsDNotAllowed) + ":" + sanitize(smpl.Labels[label], statsDNotAllowed)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
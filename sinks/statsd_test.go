@@ -0,0 +1,61 @@
+package sinks
+
+import (
+	"boshi_exporter/config"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenStatsD(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return conn
+}
+
+func sendStatsD(t *testing.T, flavor, nameTemplate string) string {
+	conn := listenStatsD(t)
+	sink, err := NewStatsDSink(&config.StatsDContext{
+		Address:      conn.LocalAddr().String(),
+		Network:      "udp",
+		Flavor:       flavor,
+		NameTemplate: nameTemplate,
+	})
+	require.NoError(t, err)
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), families, time.Now()))
+
+	packet := make([]byte, statsDMaxUDPPacketSize)
+	n, err := conn.Read(packet)
+	require.NoError(t, err)
+	return string(packet[:n])
+}
+
+func TestStatsDSink_SendDogStatsD(t *testing.T) {
+	packet := sendStatsD(t, StatsDFlavorDogStatsD, "{{ .Name }}")
+	assert.Equal(t, "boshi_monit_process_uptime_seconds:42|g|#bosh_deployment:test-dev,process_name:nats", packet)
+}
+
+func TestStatsDSink_SendNameTemplate(t *testing.T) {
+	packet := sendStatsD(t, StatsDFlavorStatsD, "{{ .Labels.bosh_deployment }}.{{ .Labels.process_name }}.{{ .Name }}{{ .Labels.missing }}")
+	assert.Equal(t, "test-dev.nats.boshi_monit_process_uptime_seconds:42|g", packet)
+}
+
+func TestStatsDSink_SendDefaultNameTemplate(t *testing.T) {
+	packet := sendStatsD(t, StatsDFlavorStatsD, "")
+	assert.Equal(t, "boshi_monit_process_uptime_seconds.test-dev.nats:42|g", packet, "the label values keep the series apart without tags")
+	packet = sendStatsD(t, StatsDFlavorDogStatsD, "")
+	assert.Equal(t, "boshi_monit_process_uptime_seconds:42|g|#bosh_deployment:test-dev,process_name:nats", packet)
+}
+
+func TestNewStatsDSink_InvalidTemplate(t *testing.T) {
+	_, err := NewStatsDSink(&config.StatsDContext{NameTemplate: "{{ .Name"})
+	assert.Error(t, err)
+}
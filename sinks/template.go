@@ -0,0 +1,41 @@
+package sinks
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateData is passed to the name templates of the sinks
+type templateData struct {
+	Name   string            // series name, e.g. boshi_monit_process_uptime_seconds
+	Labels map[string]string // series labels, e.g. .Labels.bosh_deployment or .Labels.process_name
+}
+
+// parseNameTemplate parses a name template, missing labels are rendered as empty strings
+func parseNameTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s template '%s', error: %v", name, text, err)
+	}
+	return tmpl, nil
+}
+
+// executeNameTemplate renders the name of the sample
+func executeNameTemplate(tmpl *template.Template, s *sample) (string, error) {
+	var name strings.Builder
+	if err := tmpl.Execute(&name, templateData{Name: s.Name, Labels: s.Labels}); err != nil {
+		return "", fmt.Errorf("cannot execute %s template, error: %v", tmpl.Name(), err)
+	}
+	return name.String(), nil
+}
+
+// sanitize replaces the characters not allowed by a line protocol with underscores
+func sanitize(s string, notAllowed string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || strings.ContainsRune(notAllowed, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}